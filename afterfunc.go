@@ -0,0 +1,56 @@
+package heartbeat
+
+import (
+	"context"
+	"time"
+)
+
+// AfterFunc is a resettable replacement of time.AfterFunc for idle timeouts: fn is called once in its own
+// goroutine when the timeout passes since the last Beat() call. Beat() resets the timer, like Timer.Reset() would.
+// Close() prevents fn from being called, unless it was already called.
+//
+// The timeout is checked every tenth of it, but not less often than every DefaultCheckInterval.
+func AfterFunc(ctx context.Context, timeout time.Duration, fn func()) *Heartbeat {
+	return New(ctx, timeout, &Options{
+		CheckInterval: tenth(timeout),
+		CancelHook: func(_, _, _ time.Duration) {
+			go fn()
+		},
+	})
+}
+
+// AfterTimeout arranges to call fn in its own goroutine once the Heartbeat expires, with the cause of the expiry.
+// Unlike context.AfterFunc on Ctx(), fn is not called if the context is done otherwise, e.g. by Close().
+// Calling stop stops the association, it returns true if it stopped fn from being run, the same as
+// the stop function returned by context.AfterFunc. The association is bound to the current context:
+// after Reset(), call AfterTimeout again.
+func AfterTimeout(h *Heartbeat, fn func(err *TimeoutError)) (stop func() bool) {
+	g := h.gen.Load()
+	s := g.subs.add()
+	stopf := context.AfterFunc(g.ctx, func() {
+		defer s.Unsubscribe()
+
+		// The terminal state is set before the context is done, wait for it to be notified.
+		select {
+		case <-s.Expired():
+			fn(g.timeoutErr.Load())
+		case <-s.Closed():
+		}
+	})
+
+	return func() bool {
+		stopped := stopf()
+		if stopped {
+			s.Unsubscribe()
+		}
+		return stopped
+	}
+}
+
+// tenth returns the check interval for timeout: a tenth of it, but not more than DefaultCheckInterval.
+func tenth(timeout time.Duration) time.Duration {
+	if checkInterval := timeout / 10; checkInterval < DefaultCheckInterval {
+		return checkInterval
+	}
+	return DefaultCheckInterval
+}
@@ -0,0 +1,152 @@
+package heartbeat_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+func TestAfterFunc(t *testing.T) {
+	t.Parallel()
+
+	t.Run("called once on timeout", func(t *testing.T) {
+		t.Parallel()
+
+		var calls atomic.Int64
+		h := heartbeat.AfterFunc(context.Background(), 200*time.Millisecond, func() {
+			calls.Add(1)
+		})
+		defer h.Close()
+
+		for i := 0; i < 5; i++ {
+			time.Sleep(100 * time.Millisecond)
+			h.Beat()
+		}
+		require.Zero(t, calls.Load())
+
+		require.Eventually(t, func() bool { return calls.Load() == 1 }, 300*time.Millisecond, 10*time.Millisecond)
+		time.Sleep(300 * time.Millisecond)
+		require.Equal(t, int64(1), calls.Load())
+	})
+
+	t.Run("close prevents the call", func(t *testing.T) {
+		t.Parallel()
+
+		var calls atomic.Int64
+		h := heartbeat.AfterFunc(context.Background(), 100*time.Millisecond, func() {
+			calls.Add(1)
+		})
+		h.Close()
+
+		time.Sleep(300 * time.Millisecond)
+		require.Zero(t, calls.Load())
+	})
+}
+
+func TestAfterTimeout(t *testing.T) {
+	t.Parallel()
+
+	t.Run("timeout", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), 50*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+		})
+		defer h.Close()
+		errs := make(chan *heartbeat.TimeoutError, 1)
+		heartbeat.AfterTimeout(h, func(err *heartbeat.TimeoutError) {
+			errs <- err
+		})
+
+		err := <-errs
+		require.Equal(t, heartbeat.ReasonIdle, err.Reason)
+		require.Equal(t, context.Cause(h.Ctx()), err)
+	})
+
+	t.Run("close", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), time.Minute, nil)
+		var calls atomic.Int64
+		stop := heartbeat.AfterTimeout(h, func(*heartbeat.TimeoutError) {
+			calls.Add(1)
+		})
+		h.Close()
+
+		time.Sleep(20 * time.Millisecond)
+		require.Zero(t, calls.Load())
+		require.False(t, stop())
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), 50*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+		})
+		defer h.Close()
+		var calls atomic.Int64
+		stop := heartbeat.AfterTimeout(h, func(*heartbeat.TimeoutError) {
+			calls.Add(1)
+		})
+		require.True(t, stop())
+		require.False(t, stop())
+
+		<-h.Ctx().Done()
+		time.Sleep(20 * time.Millisecond)
+		require.Zero(t, calls.Load())
+	})
+
+	t.Run("close during the cancel hook", func(t *testing.T) {
+		t.Parallel()
+
+		hooked := make(chan struct{})
+		release := make(chan struct{})
+		h := heartbeat.New(context.Background(), 50*time.Millisecond, &heartbeat.Options{
+			CheckInterval:          10 * time.Millisecond,
+			CancelHookBeforeCancel: true,
+			CancelHook: func(_, _, _ time.Duration) {
+				close(hooked)
+				<-release
+			},
+		})
+		errs := make(chan *heartbeat.TimeoutError, 1)
+		heartbeat.AfterTimeout(h, func(err *heartbeat.TimeoutError) {
+			errs <- err
+		})
+
+		<-hooked
+		// WatchAlive cancels the context before the expiry does, the Heartbeat has expired nevertheless.
+		h.WatchAlive(canceled())
+		require.Eventually(t, func() bool { return h.Ctx().Err() != nil }, time.Second, time.Millisecond)
+		close(release)
+		h.Close()
+
+		require.Equal(t, heartbeat.StateExpired, h.State())
+		require.Equal(t, heartbeat.ReasonIdle, (<-errs).Reason)
+	})
+
+	t.Run("parent deadline", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		h := heartbeat.New(ctx, time.Minute, nil)
+		defer h.Close()
+		errs := make(chan *heartbeat.TimeoutError, 1)
+		heartbeat.AfterTimeout(h, func(err *heartbeat.TimeoutError) {
+			errs <- err
+		})
+
+		require.Equal(t, heartbeat.ReasonParentDeadline, (<-errs).Reason)
+	})
+}
+
+func canceled() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}
@@ -0,0 +1,76 @@
+package heartbeat
+
+import (
+	"context"
+	"time"
+)
+
+// Builder accumulates the configuration of Heartbeats, e.g.
+//
+//	h := heartbeat.NewBuilder(time.Minute).CheckInterval(time.Second).CancelHook(report).Build(ctx)
+//
+// A Builder can build any number of Heartbeats, the later changes don't affect the Heartbeats already built.
+type Builder struct {
+	timeout time.Duration
+	opts    Options
+}
+
+// NewBuilder creates a Builder of the Heartbeats with the given timeout.
+func NewBuilder(timeout time.Duration) *Builder {
+	return &Builder{timeout: timeout}
+}
+
+// CheckInterval sets Options.CheckInterval.
+func (b *Builder) CheckInterval(interval time.Duration) *Builder {
+	b.opts.CheckInterval = interval
+	return b
+}
+
+// CheckHook sets Options.CheckHook.
+func (b *Builder) CheckHook(hook HookFn) *Builder {
+	b.opts.CheckHook = hook
+	return b
+}
+
+// CancelHook sets Options.CancelHook.
+func (b *Builder) CancelHook(hook HookFn) *Builder {
+	b.opts.CancelHook = hook
+	return b
+}
+
+// BeatHook sets Options.BeatHook.
+func (b *Builder) BeatHook(hook BeatHookFn) *Builder {
+	b.opts.BeatHook = hook
+	return b
+}
+
+// DontCancel sets Options.DontCancel.
+func (b *Builder) DontCancel() *Builder {
+	b.opts.DontCancel = true
+	return b
+}
+
+// MaxLifetime sets Options.MaxLifetime.
+func (b *Builder) MaxLifetime(lifetime time.Duration) *Builder {
+	b.opts.MaxLifetime = lifetime
+	return b
+}
+
+// With applies fn to the Options, for the options without a dedicated method.
+func (b *Builder) With(fn func(opts *Options)) *Builder {
+	fn(&b.opts)
+	return b
+}
+
+// Build creates and starts a Heartbeat, see New. Like New, it panics if the timeout is not positive.
+func (b *Builder) Build(ctx context.Context) *Heartbeat {
+	opts := b.opts
+	return New(ctx, b.timeout, &opts)
+}
+
+// BuildE is the same as Build, but it returns the error of NewE for an invalid configuration, e.g. for the timeout
+// coming from the user config.
+func (b *Builder) BuildE(ctx context.Context) (*Heartbeat, error) {
+	opts := b.opts
+	return NewE(ctx, b.timeout, &opts)
+}
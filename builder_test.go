@@ -0,0 +1,65 @@
+package heartbeat_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+func TestBuilder(t *testing.T) {
+	t.Parallel()
+
+	t.Run("build", func(t *testing.T) {
+		t.Parallel()
+
+		var checks, cancels atomic.Int64
+		b := heartbeat.NewBuilder(50 * time.Millisecond).
+			CheckInterval(10 * time.Millisecond).
+			CheckHook(func(_, _, _ time.Duration) { checks.Add(1) }).
+			CancelHook(func(_, _, _ time.Duration) { cancels.Add(1) }).
+			With(func(opts *heartbeat.Options) { opts.TrackCheckLatency = true })
+
+		h := b.Build(context.Background())
+		defer h.Close()
+
+		<-h.Ctx().Done()
+		h.Close()
+		require.ErrorIs(t, context.Cause(h.Ctx()), heartbeat.ErrTimeout)
+		require.GreaterOrEqual(t, checks.Load(), int64(3))
+		require.Equal(t, int64(1), cancels.Load())
+		require.Positive(t, h.Stats().CheckLatencyP50)
+
+		// Changes after Build don't affect the built Heartbeats.
+		b.DontCancel()
+		h2 := b.Build(context.Background())
+		defer h2.Close()
+		time.Sleep(100 * time.Millisecond)
+		require.NoError(t, h2.Ctx().Err())
+	})
+
+	t.Run("invalid timeout", func(t *testing.T) {
+		t.Parallel()
+
+		require.Panics(t, func() {
+			heartbeat.NewBuilder(0).Build(context.Background())
+		})
+	})
+
+	t.Run("build with error", func(t *testing.T) {
+		t.Parallel()
+
+		h, err := heartbeat.NewBuilder(0).BuildE(context.Background())
+		require.EqualError(t, err, "heartbeat: non-positive timeout 0s")
+		require.Nil(t, h)
+
+		_, err = heartbeat.NewBuilder(time.Second).CheckInterval(-time.Second).BuildE(context.Background())
+		require.EqualError(t, err, "heartbeat: negative CheckInterval -1s")
+
+		h, err = heartbeat.NewBuilder(time.Second).BuildE(context.Background())
+		require.NoError(t, err)
+		h.Close()
+	})
+}
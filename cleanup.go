@@ -0,0 +1,87 @@
+package heartbeat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// OnCancel registers fn to be called with the cause once the context of the Heartbeat is done,
+// for any reason: expiry, Close() or the parent context cancellation. The functions registered run
+// in the last-in, first-out order, like testing.T.Cleanup, in a goroutine of their own.
+// fn is called right away in the calling goroutine if the context is done already, its panic is not recovered.
+// A panic in a function running in the goroutine of its own doesn't prevent the others from running:
+// it is recovered and reported by OnCancelErr.
+// After Reset(), the functions are registered for the new context.
+func (h *Heartbeat) OnCancel(fn func(cause error)) {
+	if fn == nil {
+		panic("function is required")
+	}
+	h.gen.Load().cleanups.add(fn)
+}
+
+// OnCancelErr returns the panics of the functions registered by OnCancel for the current context,
+// once they have run, or nil.
+func (h *Heartbeat) OnCancelErr() error {
+	c := &h.gen.Load().cleanups
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// cleanups are the functions registered by OnCancel for a generation of a Heartbeat.
+type cleanups struct {
+	ctx   context.Context
+	mu    sync.Mutex
+	fns   []func(cause error)
+	armed bool
+	err   error // the panics of the functions, see OnCancelErr
+}
+
+func (c *cleanups) add(fn func(cause error)) {
+	c.mu.Lock()
+	if c.ctx.Err() != nil {
+		c.mu.Unlock()
+		fn(context.Cause(c.ctx))
+		return
+	}
+	c.fns = append(c.fns, fn)
+	if !c.armed {
+		c.armed = true
+		context.AfterFunc(c.ctx, c.run)
+	}
+	c.mu.Unlock()
+}
+
+func (c *cleanups) run() {
+	c.mu.Lock()
+	fns := c.fns
+	c.fns = nil
+	c.mu.Unlock()
+
+	cause := context.Cause(c.ctx)
+	var errs []error
+	for i := len(fns) - 1; i >= 0; i-- {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					errs = append(errs, panicError(r))
+				}
+			}()
+			fns[i](cause)
+		}()
+	}
+
+	c.mu.Lock()
+	c.err = errors.Join(errs...)
+	c.mu.Unlock()
+}
+
+// panicError describes the recovered panic r of an OnCancel function.
+func panicError(r any) error {
+	if err, ok := r.(error); ok {
+		return fmt.Errorf("heartbeat: OnCancel function panicked: %w", err)
+	}
+	return fmt.Errorf("heartbeat: OnCancel function panicked: %v", r)
+}
@@ -0,0 +1,157 @@
+package heartbeat_test
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+func TestHeartbeat_OnCancel(t *testing.T) {
+	t.Parallel()
+
+	t.Run("timeout", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), 50*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+		})
+		defer h.Close()
+
+		var mu sync.Mutex
+		var order []int
+		done := make(chan error, 1)
+		h.OnCancel(func(cause error) {
+			mu.Lock()
+			order = append(order, 1)
+			mu.Unlock()
+			done <- cause
+		})
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.OnCancel(func(error) {
+				mu.Lock()
+				order = append(order, 2)
+				mu.Unlock()
+			})
+		}()
+		wg.Wait()
+
+		require.ErrorIs(t, <-done, heartbeat.ErrTimeout)
+		mu.Lock()
+		require.Equal(t, []int{2, 1}, order)
+		mu.Unlock()
+
+		// Registered after done, it is called right away.
+		var cause error
+		h.OnCancel(func(err error) {
+			cause = err
+		})
+		require.ErrorIs(t, cause, heartbeat.ErrTimeout)
+	})
+
+	t.Run("close", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), time.Minute, nil)
+		done := make(chan error, 2)
+		h.OnCancel(func(cause error) {
+			done <- cause
+		})
+		h.Close()
+		h.Close()
+
+		require.ErrorIs(t, <-done, context.Canceled)
+		time.Sleep(10 * time.Millisecond)
+		require.Empty(t, done, "called once")
+	})
+
+	t.Run("parent", func(t *testing.T) {
+		t.Parallel()
+
+		errParent := errors.New("parent")
+		ctx, cancel := context.WithCancelCause(context.Background())
+		h := heartbeat.New(ctx, time.Minute, nil)
+		defer h.Close()
+		done := make(chan error, 1)
+		h.OnCancel(func(cause error) {
+			done <- cause
+		})
+		cancel(errParent)
+
+		require.ErrorIs(t, <-done, errParent)
+	})
+
+	t.Run("after done", func(t *testing.T) {
+		t.Parallel()
+
+		// Nothing was registered before, it is called right away in this goroutine.
+		h := heartbeat.New(context.Background(), time.Minute, nil)
+		h.Close()
+		var cause error
+		h.OnCancel(func(err error) {
+			cause = err
+		})
+		require.ErrorIs(t, cause, context.Canceled)
+
+		require.PanicsWithValue(t, "boom", func() {
+			h.OnCancel(func(error) {
+				panic("boom")
+			})
+		})
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), time.Minute, nil)
+		var calls sync.WaitGroup
+		calls.Add(2)
+		h.OnCancel(func(error) {
+			calls.Done()
+		})
+		h.OnCancel(func(error) {
+			panic(errors.New("boom"))
+		})
+		h.OnCancel(func(error) {
+			calls.Done()
+		})
+		require.NoError(t, h.OnCancelErr())
+		h.Close()
+
+		calls.Wait()
+		require.Eventually(t, func() bool { return h.OnCancelErr() != nil }, time.Second, 5*time.Millisecond)
+		require.EqualError(t, h.OnCancelErr(), "heartbeat: OnCancel function panicked: boom")
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), time.Minute, nil)
+		defer h.Close()
+		done := make(chan struct{})
+		h.OnCancel(func(error) {
+			close(done)
+		})
+		h.Reset()
+		<-done
+
+		again := make(chan struct{})
+		h.OnCancel(func(error) {
+			close(again)
+		})
+		time.Sleep(10 * time.Millisecond)
+		select {
+		case <-again:
+			t.Fatal("called before the new context is done")
+		default:
+		}
+		h.Close()
+		<-again
+	})
+}
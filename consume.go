@@ -0,0 +1,59 @@
+package heartbeat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrPollTimeout is matched by the error of Consume when the poll loop stalled.
+	ErrPollTimeout = errors.New("heartbeat: poll timeout")
+	// ErrHandleTimeout is matched by the error of Consume when a message handler stalled.
+	ErrHandleTimeout = errors.New("heartbeat: handle timeout")
+)
+
+// Consume runs a message consumer loop supervised in two dimensions: a poll Heartbeat with pollTimeout is beaten
+// on every successful next() call, and every handle call gets a fresh Heartbeat with handleTimeout.
+// The handler beats also beat the poll Heartbeat, so that a long but progressing handle call doesn't expire it.
+//
+// Consume returns the first error of next or handle. On expiry, the error matches ErrPollTimeout
+// or ErrHandleTimeout, telling which dimension failed, and ErrTimeout.
+// The timeouts are checked every tenth of them, but not less often than every DefaultCheckInterval.
+func Consume[M any](ctx context.Context, pollTimeout, handleTimeout time.Duration,
+	next func(ctx context.Context) (M, error), handle func(ctx context.Context, m M, beat func()) error) error {
+	if handleTimeout <= 0 {
+		panic("positive handle timeout is required")
+	}
+
+	poll := New(ctx, pollTimeout, &Options{CheckInterval: tenth(pollTimeout)})
+	defer poll.Close()
+
+	for {
+		m, err := next(poll.Ctx())
+		if err != nil {
+			return consumeResult(poll, ErrPollTimeout, err)
+		}
+		poll.Beat()
+
+		h := New(poll.Ctx(), handleTimeout, &Options{CheckInterval: tenth(handleTimeout)})
+		err = handle(h.Ctx(), m, func() {
+			h.Beat()
+			poll.Beat()
+		})
+		h.Close()
+
+		if err := consumeResult(h, ErrHandleTimeout, err); err != nil {
+			return consumeResult(poll, ErrPollTimeout, err)
+		}
+	}
+}
+
+// consumeResult returns err of an operation under h, marked with errTimeout if h expired.
+func consumeResult(h *Heartbeat, errTimeout error, err error) error {
+	if h.State() != StateExpired || h.CancelReason() == ReasonParentDeadline || errors.Is(err, ErrTimeout) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", errTimeout, h.result(err))
+}
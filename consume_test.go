@@ -0,0 +1,104 @@
+package heartbeat_test
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+func TestConsume(t *testing.T) {
+	t.Parallel()
+
+	// stall blocks until ctx is done and returns its error.
+	stall := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	t.Run("handle errors", func(t *testing.T) {
+		t.Parallel()
+
+		errHandle := errors.New("handle failed")
+		var handled []int
+
+		err := heartbeat.Consume(context.Background(), time.Second, time.Second,
+			func(ctx context.Context) (int, error) {
+				return len(handled), nil
+			},
+			func(ctx context.Context, m int, beat func()) error {
+				handled = append(handled, m)
+				if m == 2 {
+					return errHandle
+				}
+				return nil
+			})
+
+		require.ErrorIs(t, err, errHandle)
+		require.NotErrorIs(t, err, heartbeat.ErrTimeout)
+		require.Equal(t, []int{0, 1, 2}, handled)
+	})
+
+	t.Run("poll timeout", func(t *testing.T) {
+		t.Parallel()
+
+		err := heartbeat.Consume(context.Background(), 100*time.Millisecond, time.Second,
+			func(ctx context.Context) (int, error) {
+				return 0, stall(ctx)
+			},
+			func(ctx context.Context, m int, beat func()) error {
+				return nil
+			})
+
+		require.ErrorIs(t, err, heartbeat.ErrPollTimeout)
+		require.ErrorIs(t, err, heartbeat.ErrTimeout)
+		require.NotErrorIs(t, err, heartbeat.ErrHandleTimeout)
+	})
+
+	t.Run("handle timeout", func(t *testing.T) {
+		t.Parallel()
+
+		err := heartbeat.Consume(context.Background(), time.Second, 100*time.Millisecond,
+			func(ctx context.Context) (int, error) {
+				return 0, nil
+			},
+			func(ctx context.Context, m int, beat func()) error {
+				return stall(ctx)
+			})
+
+		require.ErrorIs(t, err, heartbeat.ErrHandleTimeout)
+		require.ErrorIs(t, err, heartbeat.ErrTimeout)
+		require.NotErrorIs(t, err, heartbeat.ErrPollTimeout)
+	})
+
+	t.Run("beating handler keeps polling alive", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		var polls int
+		err := heartbeat.Consume(ctx, 100*time.Millisecond, 100*time.Millisecond,
+			func(ctx context.Context) (int, error) {
+				polls++
+				if polls == 2 {
+					cancel()
+					return 0, stall(ctx)
+				}
+				return 0, nil
+			},
+			func(ctx context.Context, m int, beat func()) error {
+				for i := 0; i < 5; i++ {
+					beat()
+					time.Sleep(50 * time.Millisecond)
+				}
+				return nil
+			})
+
+		require.ErrorIs(t, err, context.Canceled)
+		require.NotErrorIs(t, err, heartbeat.ErrTimeout)
+		require.Equal(t, 2, polls)
+	})
+}
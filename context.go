@@ -0,0 +1,30 @@
+package heartbeat
+
+import "context"
+
+// contextKey is the key of the Heartbeat stored in a context.
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying h, so that the code deep in the call chain can retrieve it
+// with FromContext and beat it. The innermost Heartbeat wins if several are stored in the context chain.
+func NewContext(ctx context.Context, h *Heartbeat) context.Context {
+	return context.WithValue(ctx, contextKey{}, h)
+}
+
+// FromContext returns the Heartbeat stored in ctx by NewContext, if any.
+// Heartbeat.Ctx() and the contexts derived from it carry the Heartbeat, so NewContext is needed only
+// to pass a Heartbeat along with another context.
+func FromContext(ctx context.Context) (*Heartbeat, bool) {
+	h, ok := ctx.Value(contextKey{}).(*Heartbeat)
+	return h, ok
+}
+
+// Beat beats the Heartbeat stored in ctx by NewContext and reports whether there was one.
+// It lets library code report progress unconditionally: without a Heartbeat in ctx it is a cheap no-op.
+func Beat(ctx context.Context) bool {
+	h, ok := FromContext(ctx)
+	if ok {
+		h.Beat()
+	}
+	return ok
+}
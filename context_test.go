@@ -0,0 +1,111 @@
+package heartbeat_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+func TestFromContext(t *testing.T) {
+	t.Parallel()
+
+	t.Run("plain context", func(t *testing.T) {
+		t.Parallel()
+
+		h, ok := heartbeat.FromContext(context.Background())
+		require.False(t, ok)
+		require.Nil(t, h)
+	})
+
+	t.Run("nested heartbeats", func(t *testing.T) {
+		t.Parallel()
+
+		outer := heartbeat.New(context.Background(), time.Second, nil)
+		defer outer.Close()
+		ctx := heartbeat.NewContext(outer.Ctx(), outer)
+
+		got, ok := heartbeat.FromContext(context.WithValue(ctx, struct{}{}, "value"))
+		require.True(t, ok)
+		require.Same(t, outer, got)
+
+		inner := heartbeat.New(ctx, time.Second, nil)
+		defer inner.Close()
+		ctx = heartbeat.NewContext(inner.Ctx(), inner)
+
+		got, ok = heartbeat.FromContext(ctx)
+		require.True(t, ok)
+		require.Same(t, inner, got)
+	})
+
+	t.Run("own context", func(t *testing.T) {
+		t.Parallel()
+
+		type key struct{}
+
+		h := heartbeat.New(context.Background(), time.Second, nil)
+		defer h.Close()
+		ctx := context.WithValue(h.Ctx(), key{}, "value")
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		got, ok := heartbeat.FromContext(ctx)
+		require.True(t, ok)
+		require.Same(t, h, got)
+	})
+
+	t.Run("not with finalizer", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), time.Second, &heartbeat.Options{SetFinalizer: true})
+		defer h.Close()
+
+		_, ok := heartbeat.FromContext(h.Ctx())
+		require.False(t, ok)
+	})
+}
+
+func TestBeat(t *testing.T) {
+	t.Parallel()
+
+	t.Run("no heartbeat", func(t *testing.T) {
+		t.Parallel()
+
+		require.False(t, heartbeat.Beat(context.Background()))
+	})
+
+	t.Run("beats the heartbeat", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), time.Second, nil)
+		defer h.Close()
+		ctx := heartbeat.NewContext(h.Ctx(), h)
+
+		require.True(t, heartbeat.Beat(ctx))
+		require.Equal(t, uint64(1), h.Stats().Beats)
+	})
+}
+
+func TestBeat_noAllocs(t *testing.T) {
+	h := heartbeat.New(context.Background(), time.Second, nil)
+	defer h.Close()
+	ctx := heartbeat.NewContext(h.Ctx(), h)
+
+	require.Zero(t, testing.AllocsPerRun(100, func() {
+		heartbeat.Beat(ctx)
+	}))
+}
+
+func BenchmarkBeat(b *testing.B) {
+	h := heartbeat.New(context.Background(), time.Minute, nil)
+	defer h.Close()
+	ctx := heartbeat.NewContext(h.Ctx(), h)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		heartbeat.Beat(ctx)
+	}
+}
@@ -0,0 +1,124 @@
+package heartbeat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTimeout is matched by the cause of the Heartbeat context cancellation when the Heartbeat expires.
+// Use errors.Is(context.Cause(h.Ctx()), ErrTimeout) to tell it apart from Close() and the parent context
+// cancellation, and errors.As with *TimeoutError for the details.
+var ErrTimeout = errors.New("heartbeat: timeout")
+
+// ErrNotRunning is returned by the operations requiring a running Heartbeat, e.g. ForceExpire.
+var ErrNotRunning = errors.New("heartbeat: not running")
+
+// CancelReason tells which constraint expired the Heartbeat.
+type CancelReason int32
+
+const (
+	// ReasonNone means the Heartbeat hasn't expired.
+	ReasonNone CancelReason = iota
+	// ReasonIdle means the timeout passed since the last beat.
+	ReasonIdle
+	// ReasonMaxLifetime means Options.MaxLifetime passed since the Heartbeat creation.
+	ReasonMaxLifetime
+	// ReasonParentDeadline means the deadline of the parent context passed.
+	ReasonParentDeadline
+	// ReasonForced means the Heartbeat was expired by ForceExpire.
+	ReasonForced
+	// ReasonNoFirstBeat means Options.RequireFirstBeatWithin passed without a beat.
+	ReasonNoFirstBeat
+	// ReasonMissedSlot means the slots of Options.Schedule were missed.
+	ReasonMissedSlot
+)
+
+// String returns the snake_case name of the reason.
+func (r CancelReason) String() string {
+	switch r {
+	case ReasonNone:
+		return "none"
+	case ReasonIdle:
+		return "idle"
+	case ReasonMaxLifetime:
+		return "max_lifetime"
+	case ReasonParentDeadline:
+		return "parent_deadline"
+	case ReasonForced:
+		return "forced"
+	case ReasonNoFirstBeat:
+		return "no_first_beat"
+	case ReasonMissedSlot:
+		return "missed_slot"
+	default:
+		return "unknown"
+	}
+}
+
+// TimeoutError is the cause of the Heartbeat context cancellation when the Heartbeat expires.
+// It matches ErrTimeout with errors.Is.
+type TimeoutError struct {
+	// Name is the name of the Heartbeat, see Options.Name.
+	Name string
+	// Reason is the constraint that expired first.
+	Reason CancelReason
+	// Timeout is the configured timeout.
+	Timeout time.Duration
+	// Idle is the time passed since the last beat.
+	Idle time.Duration
+	// Phase is the phase of the operation at the expiry, see Heartbeat.SetPhase.
+	Phase string
+	// PhaseDuration is the time spent in the phase until the expiry.
+	PhaseDuration time.Duration
+	// LastBeatLabel is the label of the last labeled beat, see Heartbeat.BeatLabeled.
+	LastBeatLabel string
+	// Slot is the last missed slot for ReasonMissedSlot.
+	Slot time.Time
+	// Err is the error given to ForceExpire or the details of the expiry, e.g. a *QuorumError, if any.
+	Err error
+}
+
+func (e *TimeoutError) Error() string {
+	msg := "heartbeat: timeout"
+	if e.Name != "" {
+		msg += fmt.Sprintf(" of %q", e.Name)
+	}
+
+	switch e.Reason {
+	case ReasonMaxLifetime:
+		msg += ": max lifetime exceeded"
+	case ReasonParentDeadline:
+		msg += ": parent deadline exceeded"
+	case ReasonNoFirstBeat:
+		msg += fmt.Sprintf(": no first beat for %s", e.Idle.Truncate(time.Millisecond))
+	case ReasonForced:
+		msg += ": forced"
+	case ReasonMissedSlot:
+		msg += ": missed slot at " + e.Slot.Format(time.RFC3339Nano)
+	default:
+		msg += fmt.Sprintf(": no beat for %s", e.Idle.Truncate(time.Millisecond))
+	}
+
+	if e.LastBeatLabel != "" {
+		msg += fmt.Sprintf(" since beat %q", e.LastBeatLabel)
+	}
+	if e.Phase != "" {
+		msg += fmt.Sprintf(" in phase %q after %s", e.Phase, e.PhaseDuration.Truncate(time.Millisecond))
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns Err.
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrTimeout, or context.DeadlineExceeded for the parent deadline.
+func (e *TimeoutError) Is(target error) bool {
+	return target == ErrTimeout || (e.Reason == ReasonParentDeadline && target == context.DeadlineExceeded)
+}
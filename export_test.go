@@ -0,0 +1,22 @@
+package heartbeat
+
+import (
+	"time"
+)
+
+// Expire expires the Heartbeat the same way the timeout checks do, so that the tests can race them.
+func (h *Heartbeat) Expire() {
+	now := time.Now()
+	idle := h.idle(now)
+	h.expire(now, ReasonIdle, nil, idle, h.left(now, idle))
+}
+
+// SetExplain sets the function explaining the expiry, it is called by the expiry before the state transition.
+func (h *Heartbeat) SetExplain(explain func(now time.Time) error) {
+	h.explain = explain
+}
+
+// Wakeups returns the number of times the Manager woke up to check its Heartbeats.
+func (m *Manager) Wakeups() uint64 {
+	return m.wakeups.Load()
+}
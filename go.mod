@@ -1,6 +1,6 @@
 module ytils.dev/heartbeat
 
-go 1.19
+go 1.21
 
 require github.com/stretchr/testify v1.8.3
 
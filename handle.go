@@ -0,0 +1,89 @@
+package heartbeat
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultStopTimeout is the default time Handle.Stop() waits for the function to return.
+	DefaultStopTimeout = 5 * time.Second
+)
+
+// Handle is the handle of a function started by Go. It is safe for concurrent use.
+type Handle struct {
+	h           *Heartbeat
+	stopTimeout time.Duration
+
+	done      chan struct{}
+	err       error
+	abandoned atomic.Bool
+}
+
+// Go runs fn in a new goroutine under a Heartbeat with the given timeout and returns its Handle.
+// The Heartbeat is closed once fn returns.
+// Options.DrainTimeout bounds the wait of Handle.Stop(), DefaultStopTimeout by default.
+func Go(ctx context.Context, timeout time.Duration, opts *Options, fn RunFn) *Handle {
+	g := &Handle{
+		h:           New(ctx, timeout, opts),
+		stopTimeout: DefaultStopTimeout,
+		done:        make(chan struct{}),
+	}
+	if opts != nil && opts.DrainTimeout > 0 {
+		g.stopTimeout = opts.DrainTimeout
+	}
+
+	go func() {
+		err := fn(g.h.Ctx(), g.h.Beat)
+		g.err = g.h.result(err)
+		g.h.Close()
+		close(g.done)
+	}()
+
+	return g
+}
+
+// Done returns a channel that is closed once the function has returned.
+func (g *Handle) Done() <-chan struct{} {
+	return g.done
+}
+
+// Err returns nil while the function is running. Then it returns the error of the function,
+// or the cancellation cause (a *TimeoutError) if the Heartbeat expired, see Run.
+func (g *Handle) Err() error {
+	select {
+	case <-g.done:
+		return g.err
+	default:
+		return nil
+	}
+}
+
+// Heartbeat returns the Heartbeat supervising the function.
+func (g *Handle) Heartbeat() *Heartbeat {
+	return g.h
+}
+
+// Stop closes the Heartbeat and waits for the function to return, see Go for the bound of the wait.
+// It returns Err(), or ErrAbandoned if the function didn't return in time; it is then counted by Abandoned().
+func (g *Handle) Stop() error {
+	g.h.Close()
+
+	timer := time.NewTimer(g.stopTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-g.done:
+		return g.err
+	case <-timer.C:
+		if g.abandoned.CompareAndSwap(false, true) {
+			abandoned.Add(1)
+			go func() {
+				<-g.done
+				abandoned.Add(-1)
+			}()
+		}
+		return ErrAbandoned
+	}
+}
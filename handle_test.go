@@ -0,0 +1,94 @@
+package heartbeat_test
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+func TestGo(t *testing.T) {
+	t.Parallel()
+
+	opts := &heartbeat.Options{CheckInterval: 10 * time.Millisecond}
+
+	t.Run("result", func(t *testing.T) {
+		t.Parallel()
+
+		errOwn := errors.New("own")
+		release := make(chan struct{})
+		g := heartbeat.Go(context.Background(), time.Second, opts, func(ctx context.Context, beat func()) error {
+			<-release
+			return errOwn
+		})
+
+		require.NoError(t, g.Err())
+		require.NoError(t, g.Heartbeat().Ctx().Err())
+
+		close(release)
+		<-g.Done()
+		require.ErrorIs(t, g.Err(), errOwn)
+		require.Equal(t, heartbeat.StateClosed, g.Heartbeat().State())
+		require.ErrorIs(t, g.Stop(), errOwn)
+	})
+
+	t.Run("expiry, concurrent polling", func(t *testing.T) {
+		t.Parallel()
+
+		g := heartbeat.Go(context.Background(), 50*time.Millisecond, opts, func(ctx context.Context, beat func()) error {
+			<-ctx.Done()
+			return nil
+		})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for g.Err() == nil {
+					time.Sleep(time.Millisecond)
+				}
+			}()
+		}
+		wg.Wait()
+
+		<-g.Done()
+		require.ErrorIs(t, g.Err(), heartbeat.ErrTimeout)
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		t.Parallel()
+
+		g := heartbeat.Go(context.Background(), time.Minute, opts, func(ctx context.Context, beat func()) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+		require.ErrorIs(t, g.Stop(), context.Canceled)
+		require.ErrorIs(t, g.Err(), context.Canceled)
+	})
+
+	t.Run("stop abandons", func(t *testing.T) {
+		t.Parallel()
+
+		release := make(chan struct{})
+		g := heartbeat.Go(context.Background(), time.Minute, &heartbeat.Options{
+			DrainTimeout: 50 * time.Millisecond,
+		}, func(ctx context.Context, beat func()) error {
+			<-release // ignores ctx
+			return nil
+		})
+
+		start := time.Now()
+		require.ErrorIs(t, g.Stop(), heartbeat.ErrAbandoned)
+		require.Less(t, time.Since(start), 500*time.Millisecond)
+		require.GreaterOrEqual(t, heartbeat.Abandoned(), int64(1))
+
+		close(release)
+		<-g.Done()
+		require.NoError(t, g.Stop())
+	})
+}
@@ -2,6 +2,11 @@ package heartbeat
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -9,6 +14,8 @@ import (
 const (
 	// DefaultCheckInterval is the default interval between timeout checks.
 	DefaultCheckInterval = time.Second
+	// DefaultCancelHookTimeout is the default bound of the cancel hooks with Options.CancelHookBeforeCancel.
+	DefaultCancelHookTimeout = time.Second
 )
 
 // HookFn is the signature of hook functions.
@@ -17,44 +24,415 @@ const (
 // left is the time left until the Heartbeat context is cancelled if there will be no Beat() call.
 type HookFn func(timeout, idle, left time.Duration)
 
+// State is the lifecycle state of the Heartbeat.
+type State int32
+
+const (
+	// StateRunning means the timeout checks are going on.
+	StateRunning State = iota
+	// StateExpired means the context was cancelled because the timeout passed.
+	StateExpired
+	// StateClosed means the context was cancelled by Close().
+	StateClosed
+	// StateCancelled means the parent context was cancelled.
+	StateCancelled
+)
+
+// String returns the lowercase name of the state.
+func (s State) String() string {
+	switch s {
+	case StateRunning:
+		return "running"
+	case StateExpired:
+		return "expired"
+	case StateClosed:
+		return "closed"
+	case StateCancelled:
+		return "cancelled"
+	default:
+		return "unknown"
+	}
+}
+
+// Beater is implemented by the types that can be beaten to report progress and that expose the context they control.
+type Beater interface {
+	// Beat reports that the operation is still making progress.
+	Beat()
+	// Ctx returns the context controlled by the Beater.
+	Ctx() context.Context
+}
+
+// Heartbeater is the minimal interface of Heartbeat, so that the code using it can be given a fake in tests,
+// see the heartbeattest package.
+type Heartbeater interface {
+	Beater
+	// Close releases the Heartbeater and cancels its context.
+	Close()
+}
+
+// HookAtFn is the signature of hook functions receiving the wall-clock time of the check,
+// e.g. to correlate the heartbeat events across hosts. The durations are the same as in HookFn.
+type HookAtFn func(now time.Time, timeout, idle, left time.Duration)
+
+// BeatHookFn is the signature of the beat hook.
+// at is the time of the Beat() call.
+// idleBefore is the time passed since the previous beat.
+type BeatHookFn func(at time.Time, idleBefore time.Duration)
+
+// NearMissHookFn is the signature of the near-miss hook, see Options.NearMissFraction.
+type NearMissHookFn func(left time.Duration)
+
 // Options defines optional parameters of Heartbeat.
+// The hooks may call Close(), it doesn't wait for the running hooks then, see Close. They must not call Reset().
 type Options struct {
+	// Name identifies the Heartbeat, e.g. in Snapshot() and in the TimeoutError.
+	Name string
+	// TimeoutScale overrides the factor set by SetTimeoutScale for this Heartbeat. It multiplies the timeout,
+	// MaxLifetime and RequireFirstBeatWithin, as well as the timeouts set by SetTimeout() and returned
+	// by TimeoutFunc. The hooks and Stats() report the scaled timeouts.
+	TimeoutScale float64
 	// CheckInterval is the interval between timeout checks.
 	CheckInterval time.Duration
 	// CheckHook is called on every timeout check.
 	CheckHook HookFn
 	// CancelHook is called when the context controlled by Heartbeat is cancelled.
+	// If the check hooks are slow, the expiry doesn't wait for them: CancelHook may be then called
+	// from another goroutine while a check hook is still running.
 	CancelHook HookFn
+	// CancelHookBeforeCancel makes the cancel hooks run before the context is cancelled on timeout,
+	// e.g. to snapshot the state of the operation before it starts tearing it down.
+	// The context is cancelled anyway once CancelHookTimeout passes, even if the hooks are still running.
+	CancelHookBeforeCancel bool
+	// CancelHookTimeout bounds the cancel hooks with CancelHookBeforeCancel, DefaultCancelHookTimeout by default.
+	CancelHookTimeout time.Duration
+	// CancelFunc bridges the Heartbeat into externally managed cancellation: it is called once on expiry
+	// with the cancellation cause, right after the context of the Heartbeat is cancelled.
+	// It is not called by Close() nor on the parent context cancellation, unless that is reported as
+	// an expiry, see ReasonParentDeadline. Cancelling the external context doesn't affect the Heartbeat,
+	// unless it is the parent context.
+	CancelFunc context.CancelCauseFunc
+	// CleanupGrace enables CleanupCtx(): a context for the cleanup after the cancellation, expiring
+	// the grace period after the context of the Heartbeat is cancelled.
+	CleanupGrace time.Duration
+	// FinalCheckOnClose makes Close() call the check hooks once more with the timings at the close,
+	// e.g. for the final snapshot of the metrics. They are not called if the Heartbeat is already done.
+	FinalCheckOnClose bool
+	// CheckHookAt is the same as CheckHook, but it also receives the time of the check.
+	// Both are called if set.
+	CheckHookAt HookAtFn
+	// CancelHookAt is the same as CancelHook, but it also receives the time of the check.
+	// Both are called if set.
+	CancelHookAt HookAtFn
+	// ParentCancelHook is called when the parent context is cancelled, unless it is reported as a timeout,
+	// see ReasonParentDeadline.
+	ParentCancelHook HookFn
+	// BeforeCheckHook and AfterCheckHook are called around every check, e.g. to wrap it in a tracing span.
+	// The check, including CheckHook or CancelHook, runs between them. AfterCheckHook receives the duration
+	// of the check since BeforeCheckHook returned.
+	BeforeCheckHook func()
+	AfterCheckHook  func(duration time.Duration)
+	// Metrics receives the number of the checks and of the expiries, the idle time at every check and
+	// the durations of the checks. Nothing is reported by default.
+	Metrics Metrics
+	// TrackCheckLatency enables tracking the durations of the checks, hooks included,
+	// to report their percentiles by Stats().
+	TrackCheckLatency bool
+	// StarvationHook is called when a periodic check is late by more than StarvationThreshold,
+	// e.g. because the checking goroutine is starved on a loaded host, which makes the timeout more lenient.
+	// late is the time since the check was due. The lateness is reported by Stats() too.
+	StarvationHook func(late time.Duration)
+	// StarvationThreshold is the lateness of a check calling StarvationHook, the check interval by default.
+	StarvationThreshold time.Duration
+	// SlowHook is called when a check, including its hooks, takes longer than CheckInterval.
+	// overrun is the time in excess. The ticks missed meanwhile are skipped, not queued.
+	SlowHook func(overrun time.Duration)
+	// NearMissFraction enables the detection of the near-miss beats: the beats arriving when the time left
+	// was below the fraction of the timeout, e.g. 0.1. They are counted by Stats().NearMissBeats.
+	NearMissFraction float64
+	// NearMissHook is called by the checks once per episode of the near-miss beats, i.e. it is called again
+	// only after a beat with enough time to spare, so that a consistently slow producer doesn't flood it.
+	// left is the time that was left until the timeout at the latest near-miss beat.
+	NearMissHook NearMissHookFn
+	// BeatTokens is the number of the recent tokens remembered by BeatToken(), DefaultBeatTokens by default.
+	BeatTokens int
+	// StateHook is called on every state transition, e.g. from StateRunning to StateExpired, and back
+	// to StateRunning by Reset(). It is called synchronously by the goroutine making the transition,
+	// before the context is cancelled and before the other hooks.
+	StateHook func(from, to State)
+	// RateHook is called on every check with the rate of the beats over the trailing RateWindow,
+	// e.g. for the producers throttling themselves.
+	RateHook RateHookFn
+	// RateWindow is the window of the rate reported by RateHook, the check interval by default.
+	// The rate is measured between the checks, so it covers at least the window.
+	RateWindow time.Duration
+	// BeatHook is called on every Beat() call, in the goroutine calling Beat().
+	BeatHook BeatHookFn
+	// ForwardBeatsTo is beaten on every beat of the Heartbeat, e.g. to count the beats of a sub-operation
+	// for the job-level Heartbeat too, see also Tee. The beats ignored by the Heartbeat are not forwarded.
+	// The forwarding must not form a cycle.
+	ForwardBeatsTo Beater
+	// SuspendWhenIdle makes the idle timeout suspend the Heartbeat rather than expire it: the checking goroutine
+	// exits and the next beat starts it again, see Suspended. The context stays alive while suspended,
+	// it is still cancelled by Close() and by the parent context. The other constraints, e.g. MaxLifetime,
+	// are not enforced while suspended. It trades the latency of the first beat after the suspension,
+	// which starts a goroutine, for no memory nor wakeups taken by the dormant Heartbeats.
+	// It is ignored by the Heartbeats checked by a Manager, which have no goroutine of their own.
+	SuspendWhenIdle bool
+	// DontCancel turns the Heartbeat into a pure alerting mechanism: on timeout CancelHook is called,
+	// but the context is not cancelled and the checks go on. CancelHook is called once per stall,
+	// i.e. it is called again only if the timeout passes again after a subsequent Beat() call.
+	// The user is responsible for acting on the notification, e.g. by calling Close() outside of the hook.
+	// The missed slots of Schedule are reported the same way, once per missed slot beyond Schedule.MaxMissed.
+	DontCancel bool
+	// TimeoutFunc, if set, is called on every check and its result overrides the timeout until the next check,
+	// e.g. for load-aware timeouts. The static timeout is used when it returns zero or negative duration.
+	TimeoutFunc func() time.Duration
+	// ClampToParent clamps the time left reported by the hooks, Remaining() and Deadline() to the parent deadline,
+	// if it is earlier than the timeout. The expiry by the parent deadline is still reported
+	// as ReasonParentDeadline.
+	ClampToParent bool
+	// StricterCancelsParent makes the expiry of the Heartbeats derived by Stricter() expire this one too,
+	// with ReasonForced wrapping their cause. Only the derived one is cancelled by default.
+	StricterCancelsParent bool
+	// DontBeatOnPhase makes SetPhase() only record the phase, it beats the Heartbeat by default.
+	DontBeatOnPhase bool
+	// MaxLifetime is the maximum lifetime of the Heartbeat context regardless of the beats.
+	MaxLifetime time.Duration
+	// ConsecutiveMissesToCancel is the number of the consecutive checks finding the timeout passed
+	// required to expire the Heartbeat, 1 by default, e.g. to tolerate a brief stall. Any beat starts
+	// the count over. The check hooks are called by the checks not expiring it.
+	// It applies to the idle timeout only, the other constraints expire the Heartbeat on the first check.
+	ConsecutiveMissesToCancel int
+	// SoftTimeoutHook is called once per stall when the timeout passes, e.g. to ask the operation to stop
+	// gracefully. See EscalationDelay.
+	SoftTimeoutHook HookFn
+	// EscalationDelay postpones the expiry by the idle timeout: the Heartbeat expires only if it isn't beaten
+	// within the delay after the timeout, i.e. after SoftTimeoutHook was called. A beat during the delay
+	// cancels the escalation. The hooks, Remaining() and Deadline() still report the time left until the timeout.
+	EscalationDelay time.Duration
+	// Schedule makes the Heartbeat expire with ReasonMissedSlot when Schedule.MaxMissed consecutive slots
+	// are not beaten within the tolerance, see also DontCancel. The timeout still applies, e.g. as a backstop longer
+	// than the interval.
+	// The beats are timed when they are recorded, use BeatAt for the time of a remote event.
+	Schedule *Schedule
+	// RequireFirstBeatWithin expires the Heartbeat with ReasonNoFirstBeat if it isn't beaten within the duration
+	// since the creation, see UserBeaten, e.g. to catch the operations stalling before any progress
+	// without waiting for the full timeout.
+	RequireFirstBeatWithin time.Duration
+	// DrainTimeout is how long Run waits for the operation to return after the Heartbeat expiry
+	// before abandoning it. Run waits until the operation returns by default.
+	DrainTimeout time.Duration
+	// SetFinalizer makes the Heartbeat cancel its context when it is garbage collected without Close() call.
+	// It is a safety net against goroutine leaks in buggy code, not a substitute for Close().
+	// The finalizer never runs while the Heartbeat is referenced, e.g. by hooks or by the goroutines
+	// of the helpers like TouchFile.
+	SetFinalizer bool
 }
 
 // Heartbeat holds the context Ctx() that is cancelled after the timeout passes since the last Beat() call.
+// A nil *Heartbeat does nothing, e.g. for the optional heartbeating: Beat() and Close() are no-ops,
+// Ctx() returns context.Background() and Remaining() never runs out.
 type Heartbeat struct {
-	timeout       time.Duration
+	// The state is kept separately, so that the monitor goroutine doesn't keep the Heartbeat reachable,
+	// see Options.SetFinalizer.
+	*heartbeat
+}
+
+type heartbeat struct {
+	name          string
+	timeoutFunc   func() time.Duration
 	checkInterval time.Duration
+	scale         float64
 	checkHook     HookFn
 	cancelHook    HookFn
+	checkHookAt   HookAtFn
+	cancelHookAt  HookAtFn
+	parentHook    HookFn
+	hookFirst     bool
+	cancelFunc    context.CancelCauseFunc
+	clampToParent bool
+	cleanupGrace  time.Duration
+	finalCheck    bool
+	hookTimeout   time.Duration
+	slowHook      func(overrun time.Duration)
+	starveHook    func(late time.Duration)
+	starveAfter   time.Duration
+	latency       *latencyHistogram
+	metrics       Metrics
+	beforeCheck   func()
+	afterCheck    func(duration time.Duration)
+	guarded       bool
+	beatHook      BeatHookFn
+	forward       Beater
+	stateHook     func(from, to State)
+	nearMissHook  NearMissHookFn
+	nearMiss      float64
+	dontCancel    bool
+	lazy          bool
+	phaseBeat     bool
+	strictParent  bool
+	maxLifetime   time.Duration
+	firstBeat     time.Duration
+	escalation    time.Duration
+	debounce      int
+	softHook      HookFn
+	rateHook      RateHookFn
+	schedule      *schedule
+	rate          *rateMeter
+
+	parent  context.Context
+	gen     atomic.Pointer[generation]
+	created time.Time
+	// embed makes the contexts carry the Heartbeat, see FromContext.
+	embed bool
+	// restart starts checking a new generation, see Reset. It is set by start() or by the Manager.
+	restart func()
+	// explain returns the details of the expiry wrapped by the cause, e.g. by Quorum.
+	explain func(now time.Time) error
+
+	lastBeat atomic.Int64 // time.Duration since created, so that beats don't allocate
+	born     atomic.Int64 // time.Duration since created when the current generation started, see Reset
+	timeout  atomic.Int64 // see SetTimeout
+	current  atomic.Int64 // the timeout in effect, see Options.TimeoutFunc
+	counter  atomic.Pointer[boundCounter]
+	beaten   atomic.Bool // see UserBeaten
+	paused   atomic.Bool // see Pause
+	dormant  atomic.Bool // see Suspended
+	phase    atomic.Pointer[phase]
+	label    atomic.Pointer[beatLabel]
+	state    atomic.Int32
+	reason   atomic.Int32
+	beats    atomic.Uint64
+	checks   atomic.Uint64
+	checkAt  atomic.Int64 // time.Duration since created, see NextCheckIn
+
+	acceptedEvents atomic.Uint64
+	rejectedEvents atomic.Uint64
+	beatsAfterDone atomic.Uint64 // the beats ignored in a terminal state
+	nearMisses     atomic.Uint64 // see Options.NearMissFraction
+	nearMissLeft   atomic.Int64  // the time left at the latest near-miss beat
+	skewedBeats    atomic.Uint64 // see BeatAt
+
+	lateSum   atomic.Int64 // see observeLateness
+	lateCount atomic.Int64
+	lateMax   atomic.Int64
+
+	acceptedTokens  atomic.Uint64 // see BeatToken
+	duplicateTokens atomic.Uint64
+	tokens          tokenCache
 
-	ctx       context.Context
-	cancelCtx context.CancelFunc
+	// followers count the beats of h along, see Stricter.
+	followers atomic.Pointer[[]*heartbeat]
+	// strictOf is the Heartbeat this one was derived from by Stricter, its beats count as the beats of this one.
+	strictOf *heartbeat
 
-	lastBeat atomic.Pointer[time.Time]
+	// mu is held by the checks and the terminal bookkeeping, so that Close() can wait for them.
+	mu sync.Mutex
+	// suspended is set while the checking goroutine is gone, see Options.SuspendWhenIdle.
+	// unsuspend stops waking the Heartbeat up when its context is done. They are guarded by mu.
+	suspended bool
+	unsuspend func() bool
+	// lifecycle serializes Close() and Reset().
+	lifecycle sync.Mutex
+	// own is set if the Heartbeat is checked by its own goroutine rather than by a Manager.
+	own bool
+	// wake makes the checks re-evaluate the Heartbeat right away, e.g. when the deadline moves earlier.
+	// It doesn't block, it is set by start() or by the Manager.
+	wake func()
+
+	// guardTimer expires the Heartbeat on time while the checks run the user functions, it is created
+	// by the first guarded check and reused by the next ones, see armGuard. guardOn is set while a check
+	// has armed it. They are guarded by guardMu.
+	guardMu    sync.Mutex
+	guardTimer *time.Timer
+	guardOn    bool
+
+	// hooking is the number of the hooks running, closing is set by Close() not waiting for them.
+	// See enterHook.
+	hooking atomic.Int32
+	closing atomic.Bool
+
+	// notified is the last beat for which the timeout was reported in DontCancel mode.
+	// It is owned by the checking goroutine.
+	notified int64
+	// nearMissSeen and beatsSeen are the counts observed by the previous check, nearMissEpisode is set
+	// while the near-misses go on. They are owned by the checking goroutine.
+	nearMissSeen    uint64
+	beatsSeen       uint64
+	nearMissEpisode bool
+	// misses is the number of the consecutive checks finding the timeout passed since the beat missedBeat,
+	// see Options.ConsecutiveMissesToCancel. They are owned by the checking goroutine.
+	misses     int
+	missedBeat int64
+	// softNotified is the last beat for which SoftTimeoutHook was called, it is owned by the checking goroutine.
+	softNotified int64
 }
 
 // New creates a new Heartbeat instance with the copy of the given context.
+// It panics for a nil context, a non-positive timeout, or an invalid TimeoutScale or Schedule.Interval
+// of config, e.g. for a hard-coded timeout. Unlike NewE, it ignores the other invalid fields
+// of config, see Options.Validate.
 func New(ctx context.Context, timeout time.Duration, config *Options) *Heartbeat {
+	h := newHeartbeat(ctx, timeout, config)
+	h.start()
+
+	return h
+}
+
+// NewE is the same as New, but it returns an error for a nil context, a non-positive timeout
+// or an invalid config, see Options.Validate, e.g. for the timeouts coming from the user config.
+func NewE(ctx context.Context, timeout time.Duration, config *Options) (*Heartbeat, error) {
+	h, err := buildHeartbeat(ctx, timeout, config, true)
+	if err != nil {
+		return nil, err
+	}
+	h.start()
+
+	return h, nil
+}
+
+// newHeartbeat creates a Heartbeat without starting the timeout checks, it panics like New.
+func newHeartbeat(ctx context.Context, timeout time.Duration, config *Options) *Heartbeat {
+	h, err := buildHeartbeat(ctx, timeout, config, false)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+// buildHeartbeat creates a Heartbeat without starting the timeout checks, config is validated strictly
+// for NewE, see Options.Validate.
+func buildHeartbeat(ctx context.Context, timeout time.Duration, config *Options, strict bool) (*Heartbeat, error) {
+	if ctx == nil {
+		return nil, errors.New("heartbeat: nil context")
+	}
 	if timeout <= 0 {
-		panic("positive timeout is required")
+		return nil, fmt.Errorf("heartbeat: non-positive timeout %s", timeout)
+	}
+	if err := config.validate(strict); err != nil {
+		return nil, err
 	}
 
-	hctx, cancel := context.WithCancel(ctx)
-	h := &Heartbeat{
-		ctx:           hctx,
-		cancelCtx:     cancel,
+	h := &Heartbeat{&heartbeat{
+		parent:        ctx,
+		created:       time.Now(),
 		checkInterval: DefaultCheckInterval,
-		timeout:       timeout,
+		scale:         TimeoutScale(),
+		notified:      -1,
+		softNotified:  -1,
+		embed:         true,
+		phaseBeat:     true,
+	}}
+	if config != nil && config.TimeoutScale != 0 {
+		h.scale = config.TimeoutScale
 	}
+	timeout = h.scaled(timeout)
+	h.timeout.Store(int64(timeout))
+	h.current.Store(int64(timeout))
+	h.tokens.size = DefaultBeatTokens
 
 	if config != nil {
+		h.name = config.Name
 		if config.CheckInterval > 0 {
 			h.checkInterval = config.CheckInterval
 		}
@@ -64,59 +442,1286 @@ func New(ctx context.Context, timeout time.Duration, config *Options) *Heartbeat
 		if config.CancelHook != nil {
 			h.cancelHook = config.CancelHook
 		}
+		h.checkHookAt = config.CheckHookAt
+		h.cancelHookAt = config.CancelHookAt
+		h.parentHook = config.ParentCancelHook
+		h.hookFirst = config.CancelHookBeforeCancel
+		h.cancelFunc = config.CancelFunc
+		h.clampToParent = config.ClampToParent
+		h.cleanupGrace = config.CleanupGrace
+		h.finalCheck = config.FinalCheckOnClose
+		h.hookTimeout = config.CancelHookTimeout
+		if h.hookTimeout <= 0 {
+			h.hookTimeout = DefaultCancelHookTimeout
+		}
+		h.slowHook = config.SlowHook
+		h.beforeCheck = config.BeforeCheckHook
+		h.afterCheck = config.AfterCheckHook
+		if config.TrackCheckLatency {
+			h.latency = &latencyHistogram{}
+		}
+		h.metrics = config.Metrics
+		h.starveHook = config.StarvationHook
+		h.starveAfter = config.StarvationThreshold
+		h.beatHook = config.BeatHook
+		h.forward = config.ForwardBeatsTo
+		if config.BeatTokens > 0 {
+			h.tokens.size = config.BeatTokens
+		}
+		h.stateHook = config.StateHook
+		h.nearMiss = config.NearMissFraction
+		h.nearMissHook = config.NearMissHook
+		h.dontCancel = config.DontCancel
+		h.lazy = config.SuspendWhenIdle
+		h.phaseBeat = !config.DontBeatOnPhase
+		h.strictParent = config.StricterCancelsParent
+		h.maxLifetime = h.scaled(config.MaxLifetime)
+		h.firstBeat = h.scaled(config.RequireFirstBeatWithin)
+		h.escalation = config.EscalationDelay
+		h.debounce = config.ConsecutiveMissesToCancel
+		h.softHook = config.SoftTimeoutHook
+		if config.Schedule != nil {
+			h.schedule = newSchedule(*config.Schedule, h.created)
+		}
+		if config.RateHook != nil {
+			h.rateHook = config.RateHook
+			h.rate = &rateMeter{
+				window:  config.RateWindow,
+				samples: []rateSample{{at: h.created}},
+			}
+		}
+		h.timeoutFunc = config.TimeoutFunc
+		h.guarded = !h.dontCancel && (h.checkHook != nil || h.checkHookAt != nil || h.beforeCheck != nil ||
+			h.afterCheck != nil || h.slowHook != nil || h.timeoutFunc != nil || h.softHook != nil ||
+			h.rateHook != nil || h.nearMissHook != nil || h.metrics != nil)
+		if config.SetFinalizer {
+			// The finalizer would never run if the Heartbeat was reachable from its own context.
+			h.embed = false
+			runtime.SetFinalizer(h, (*Heartbeat).Close)
+		}
 	}
 
-	h.start()
+	h.gen.Store(h.newGeneration(false))
 
-	return h
+	return h, nil
+}
+
+// generation is the context of a Heartbeat, a new one is created on every Reset().
+type generation struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	// cleanup is the context returned by CleanupCtx(), see Options.CleanupGrace.
+	cleanup context.Context
+	// tickc is the channel returned by TickC(), created on demand. It is guarded by mu.
+	tickc chan CheckResult
+	subs  subscriptions
+	// timeoutErr is the cause of the expiry, if any.
+	timeoutErr atomic.Pointer[TimeoutError]
+	// cleanups are registered by OnCancel.
+	cleanups cleanups
+	// exited is closed when the checking goroutine exits, it is nil for the Heartbeats checked by a Manager.
+	exited chan struct{}
+}
+
+func (h *Heartbeat) newGeneration(own bool) *generation {
+	ctx, cancel := context.WithCancelCause(h.parent)
+	if h.embed {
+		ctx = NewContext(ctx, h)
+	}
+
+	g := &generation{ctx: ctx, cancel: cancel, cleanup: ctx}
+	g.cleanups.ctx = ctx
+	if h.cleanupGrace > 0 {
+		cleanup, cancelCleanup := context.WithCancelCause(context.WithoutCancel(h.parent))
+		grace := h.cleanupGrace
+		context.AfterFunc(ctx, func() {
+			time.AfterFunc(grace, func() {
+				cancelCleanup(context.DeadlineExceeded)
+			})
+		})
+		g.cleanup = cleanup
+	}
+	if own {
+		g.exited = make(chan struct{})
+	}
+	return g
+}
+
+// closeTicks closes the channel returned by TickC(), if any, h.mu must be held.
+func (g *generation) closeTicks() {
+	if g.tickc != nil {
+		close(g.tickc)
+		g.tickc = nil
+	}
+}
+
+// hctx returns the context of the current generation.
+func (h *heartbeat) hctx() context.Context {
+	return h.gen.Load().ctx
+}
+
+// cancelCtx cancels the context of the current generation.
+func (h *heartbeat) cancelCtx(cause error) {
+	h.gen.Load().cancel(cause)
+}
+
+// Name returns Options.Name.
+func (h *Heartbeat) Name() string {
+	return h.name
 }
 
 // Ctx returns the child context controlled by the Heartbeat.
+// It returns the same context on every call, so it can be cached, until Reset() replaces it with a new one.
+// The context carries the Heartbeat itself, see FromContext, unless Options.SetFinalizer is set.
 func (h *Heartbeat) Ctx() context.Context {
-	return h.ctx
+	if h == nil {
+		return context.Background()
+	}
+	return h.hctx()
+}
+
+// Parent returns the parent context given to New, e.g. to derive the sibling contexts with the same ancestry.
+func (h *Heartbeat) Parent() context.Context {
+	return h.parent
+}
+
+// CleanupCtx returns the context for the cleanup after the cancellation, e.g. to write a checkpoint
+// from CancelHook. It carries the values of the parent context, but it is cancelled neither by the Heartbeat
+// nor by the parent: it is cancelled with the cause context.DeadlineExceeded Options.CleanupGrace after Ctx() is done,
+// whatever the reason.
+// Without Options.CleanupGrace, it is Ctx().
+func (h *Heartbeat) CleanupCtx() context.Context {
+	return h.gen.Load().cleanup
 }
 
 // Beat tells the Heartbeat that the operation is still making progress
 // and resets the timer towards the timeout.
+// Once the Heartbeat is expired, closed or cancelled, Beat does nothing but count the beat in Stats().BeatsAfterDone,
+// which helps to find the operations not checking their context.
 func (h *Heartbeat) Beat() {
+	if h == nil {
+		return
+	}
+	h.beat(time.Now())
+}
+
+// BeatAt is the same as Beat, but the beat happened at t, e.g. the time of a remote event.
+// A t before the last beat is ignored. A t in the future, e.g. because of a clock skew between hosts,
+// is clamped to now and counted in Stats().SkewedBeats.
+func (h *Heartbeat) BeatAt(t time.Time) {
+	if h.afterDone() {
+		return
+	}
+
+	if now := time.Now(); t.After(now) {
+		h.skewedBeats.Add(1)
+		t = now
+	}
+
+	beat := int64(t.Sub(h.created))
+	for {
+		prev := h.lastBeat.Load()
+		if beat <= prev {
+			return
+		}
+		if h.lastBeat.CompareAndSwap(prev, beat) {
+			h.recordBeat(t, time.Duration(beat-prev))
+			return
+		}
+	}
+}
+
+// BindCounter makes the checks treat any change of the counter at p since the previous check as a beat,
+// so that the fastest producers can report progress without calling into the package, e.g. with p.Add(1).
+// The tradeoff is precision: the beat is registered at the check time, so the idle time may be overestimated
+// by up to the check interval. The beat hook is called from the checking goroutine.
+// A nil p unbinds the counter.
+func (h *Heartbeat) BindCounter(p *atomic.Uint64) {
+	if p == nil {
+		h.counter.Store(nil)
+		return
+	}
+	h.counter.Store(&boundCounter{p: p, seen: p.Load()})
+}
+
+// boundCounter is the counter bound by BindCounter.
+type boundCounter struct {
+	p    *atomic.Uint64
+	seen uint64 // owned by the checks
+}
+
+func (h *heartbeat) beat(now time.Time) {
+	if h.afterDone() {
+		return
+	}
+
+	beat := now.Sub(h.created)
+	prev := time.Duration(h.lastBeat.Swap(int64(beat)))
+	h.recordBeat(now, beat-prev)
+}
+
+// beatLocked is beat for the checks holding h.mu: the suspended Heartbeat is started again in place,
+// wakeUp would deadlock on h.mu.
+func (h *heartbeat) beatLocked(now time.Time) {
+	if h.suspended {
+		h.unsuspendLocked()
+	}
+	h.beat(now)
+}
+
+// recordBeat accounts for the beat at now, idleBefore is the time passed since the previous beat.
+func (h *heartbeat) recordBeat(now time.Time, idleBefore time.Duration) {
+	if h.dormant.Load() {
+		h.wakeUp()
+	}
+	h.beats.Add(1)
+	h.markBeaten()
+	if h.schedule != nil {
+		h.schedule.beat(now)
+	}
+
+	if h.nearMiss > 0 {
+		timeout := h.currentTimeout()
+		if left := timeout - idleBefore; left > 0 && float64(left) < h.nearMiss*float64(timeout) {
+			h.nearMissLeft.Store(int64(left))
+			h.nearMisses.Add(1)
+		}
+	}
+
+	if h.beatHook != nil && h.enterHook() {
+		h.beatHook(now, idleBefore)
+		h.exitHook()
+	}
+	if h.forward != nil {
+		h.forward.Beat()
+	}
+	if f := h.followers.Load(); f != nil {
+		// The followers read the time of the beat from h, see lastBeatSince.
+		for _, follower := range *f {
+			if !follower.afterDone() {
+				follower.beats.Add(1)
+			}
+		}
+	}
+}
+
+// BeatWeight reports partial progress of weight w, which is clamped to [0, 1].
+// The last beat time moves towards now proportionally to w: lastBeat += w * (now - lastBeat).
+// So a full beat (w=1) is the same as Beat(), w=0.5 halves the idle time and w=0 does nothing.
+func (h *Heartbeat) BeatWeight(w float64) {
+	if w >= 1 {
+		h.Beat()
+		return
+	}
+	if !(w > 0) || h.afterDone() {
+		return
+	}
+
+	for {
+		last := h.lastBeat.Load()
+		now := time.Now()
+		idle := now.Sub(h.created) - time.Duration(last)
+		beat := last + int64(w*float64(idle))
+		if h.lastBeat.CompareAndSwap(last, beat) {
+			h.recordBeat(now, idle)
+			return
+		}
+	}
+}
+
+// BeatThrottled is the same as Beat, unless less than minInterval passed since the last beat:
+// such a beat is dropped, e.g. to beat from a hot loop without the cost of the hooks on every iteration.
+// It reports whether the beat was counted. A non-positive minInterval never throttles.
+func (h *Heartbeat) BeatThrottled(minInterval time.Duration) bool {
+	if h.afterDone() {
+		return false
+	}
+
+	for {
+		last := h.lastBeat.Load()
+		now := time.Now()
+		beat := int64(now.Sub(h.created))
+		if beat-last < int64(minInterval) {
+			return false
+		}
+		if h.lastBeat.CompareAndSwap(last, beat) {
+			h.recordBeat(now, time.Duration(beat-last))
+			return true
+		}
+	}
+}
+
+// SetPhase records the name of the current phase of the operation, e.g. "uploading artifacts",
+// to be reported by Stats() and by the TimeoutError on expiry. It beats the Heartbeat too,
+// unless Options.DontBeatOnPhase is set.
+func (h *Heartbeat) SetPhase(name string) {
+	h.phase.Store(&phase{name: name, since: time.Now()})
+	if h.phaseBeat {
+		h.Beat()
+	}
+}
+
+// Phase returns the name of the current phase, empty if SetPhase wasn't called.
+func (h *Heartbeat) Phase() string {
+	if p := h.phase.Load(); p != nil {
+		return p.name
+	}
+	return ""
+}
+
+// phase is the phase set by SetPhase.
+type phase struct {
+	name  string
+	since time.Time
+}
+
+// BeatLabeled is the same as Beat, but it also records what the beat was about, e.g. the stage of
+// the operation, to be reported by Stats(), the checks and the TimeoutError on expiry.
+// Beat() keeps the label of the last labeled beat. Reusing the same label doesn't allocate.
+func (h *Heartbeat) BeatLabeled(label string) {
+	if h.afterDone() {
+		return
+	}
+	if l := h.label.Load(); l == nil || l.label != label {
+		h.label.Store(&beatLabel{label: label})
+	}
+	h.beat(time.Now())
+}
+
+// LastBeatLabel returns the label of the last BeatLabeled() call, empty if there was none.
+func (h *Heartbeat) LastBeatLabel() string {
+	return h.lastLabel()
+}
+
+func (h *heartbeat) lastLabel() string {
+	if l := h.label.Load(); l != nil {
+		return l.label
+	}
+	return ""
+}
+
+// beatLabel is the label set by BeatLabeled.
+type beatLabel struct {
+	label string
+}
+
+// UserBeaten reports whether the Heartbeat was ever beaten, not counting the implicit beat on creation.
+// Reset() starts over.
+// A Heartbeat never beaten by the operation it supervises usually means the beats are not wired up.
+func (h *Heartbeat) UserBeaten() bool {
+	return h.beaten.Load()
+}
+
+func (h *heartbeat) markBeaten() {
+	if !h.beaten.Load() {
+		h.beaten.Store(true)
+	}
+}
+
+// afterDone reports whether the Heartbeat is in a terminal state and counts the beat as a beat after done if so.
+func (h *heartbeat) afterDone() bool {
+	if State(h.state.Load()) == StateRunning {
+		return false
+	}
+	h.beatsAfterDone.Add(1)
+	return true
+}
+
+// State returns the current lifecycle state of the Heartbeat.
+func (h *Heartbeat) State() State {
+	return State(h.state.Load())
+}
+
+// CancelReason returns the reason of the context cancellation by the Heartbeat,
+// ReasonNone if it wasn't cancelled by the Heartbeat (yet).
+func (h *Heartbeat) CancelReason() CancelReason {
+	return CancelReason(h.reason.Load())
+}
+
+// Healthy reports whether the Heartbeat is running and has been beaten within the timeout.
+// It is always false once the context is done.
+func (h *Heartbeat) Healthy() bool {
+	return h.hctx().Err() == nil && h.Remaining() > 0
+}
+
+// Remaining returns the time left until the timeout passes if there will be no Beat() call.
+// It is negative or zero once the timeout has passed.
+func (h *Heartbeat) Remaining() time.Duration {
+	if h == nil {
+		return math.MaxInt64
+	}
+	now := time.Now()
+	return h.left(now, h.idle(now))
+}
+
+// Deadline returns the time the timeout passes if there will be no Beat() call,
+// clamped to the parent deadline with Options.ClampToParent.
+func (h *Heartbeat) Deadline() time.Time {
 	now := time.Now()
-	h.lastBeat.Store(&now)
+	return now.Add(h.left(now, h.idle(now)))
+}
+
+// WithBudget derives a context from ctx with the deadline at Deadline(), e.g. for the downstream calls
+// not to outlive the patience of the Heartbeat. The deadline is a snapshot, it is not moved by later beats.
+func (h *Heartbeat) WithBudget(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithDeadline(ctx, h.Deadline())
+}
+
+// Derive returns a cancellable child of Ctx(), e.g. for a sub-task that may be cancelled on its own
+// but must die with the Heartbeat. It carries the Heartbeat like Ctx() does, see FromContext.
+func (h *Heartbeat) Derive() (context.Context, context.CancelFunc) {
+	return context.WithCancel(h.Ctx())
+}
+
+// Fraction returns the fraction of the timeout still remaining, from 1 right after a Beat() call
+// down to 0 once the timeout has passed.
+func (h *Heartbeat) Fraction() float64 {
+	left := h.Remaining()
+	if left <= 0 {
+		return 0
+	}
+	if left >= h.currentTimeout() {
+		return 1
+	}
+	return float64(left) / float64(h.currentTimeout())
+}
+
+// SetTimeout changes the timeout. If the deadline moves earlier, it is re-evaluated right away
+// rather than on the next check. With Options.TimeoutFunc, it changes the timeout used as the fallback.
+func (h *Heartbeat) SetTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		panic("positive timeout is required")
+	}
+
+	timeout = h.scaled(timeout)
+	prev := time.Duration(h.timeout.Swap(int64(timeout)))
+	if h.timeoutFunc != nil {
+		return
+	}
+	h.current.Store(int64(timeout))
+	if timeout < prev {
+		h.wake()
+	}
+}
+
+// ForceExpire expires the Heartbeat right away, as if the timeout passed, e.g. for a kill switch
+// or to test the timeout handling without waiting. The cancellation cause is a *TimeoutError with ReasonForced
+// wrapping err, which may be nil. The cancel hooks are called with the actual idle and left times,
+// even with Options.DontCancel the context is cancelled.
+// It returns ErrNotRunning if the Heartbeat has already expired, been closed or cancelled.
+func (h *Heartbeat) ForceExpire(err error) error {
+	now := time.Now()
+	idle := h.idle(now)
+	if !h.expire(now, ReasonForced, err, idle, h.left(now, idle)) {
+		return ErrNotRunning
+	}
+	return nil
+}
+
+// Pause suspends the timeout enforcement, e.g. for a maintenance window: the checks neither expire the Heartbeat
+// nor call the hooks until Resume(). The cancellation of the parent context still applies.
+func (h *Heartbeat) Pause() {
+	h.paused.Store(true)
+}
+
+// Resume resumes the timeout enforcement after Pause(), the time paused doesn't count as idle.
+func (h *Heartbeat) Resume() {
+	h.resume()
+}
+
+func (h *heartbeat) resume() {
+	if h.paused.Swap(false) {
+		h.lastBeat.Store(int64(time.Since(h.created)))
+	}
+}
+
+// Paused reports whether the Heartbeat is paused, see Pause.
+func (h *Heartbeat) Paused() bool {
+	return h.paused.Load()
 }
 
 // Close cancels the context controlled by the Heartbeat and stops the timeout checks.
 // Close must always be called after the operation, whether it timeouted or not, to avoid leaking goroutines.
+// It waits for the check in progress and for the checking goroutine to exit, so no hook is called once it returns.
+// No check started after Close() is called calls the check hooks, except Options.FinalCheckOnClose.
+// If a hook is running, e.g. the hook calling Close, it doesn't wait: the running hooks may return after it,
+// but no hook is entered afterwards.
 func (h *Heartbeat) Close() {
-	h.cancelCtx()
+	if h == nil {
+		return
+	}
+	h.closeWait()
+}
+
+// closeWait is the body of Close, on heartbeat so that the goroutines closing it don't keep
+// the Heartbeat reachable, see Options.SetFinalizer.
+func (h *heartbeat) closeWait() {
+	if h.hooking.Load() > 0 {
+		// Waiting for the check calling the hook would deadlock if it is the caller.
+		h.close(nil)
+		h.closing.Store(true)
+		return
+	}
+
+	h.lifecycle.Lock()
+	defer h.lifecycle.Unlock()
+
+	// Closing under mu waits for the check in progress, the later ones see the state closed and call no hooks.
+	h.mu.Lock()
+	if h.finalCheck && State(h.state.Load()) == StateRunning && h.hctx().Err() == nil {
+		now := time.Now()
+		idle := h.idle(now)
+		h.onCheck(now, idle, h.left(now, idle))
+	}
+	h.transition(StateClosed)
+	h.mu.Unlock()
+
+	h.shutdown()
+}
+
+// enterHook counts a hook running until exitHook, so that Close() called by it doesn't wait for the check
+// calling it. It reports whether the hook may be called: no hook is entered once Close() didn't wait.
+func (h *heartbeat) enterHook() bool {
+	h.hooking.Add(1)
+	if h.closing.Load() {
+		h.hooking.Add(-1)
+		return false
+	}
+	return true
+}
+
+func (h *heartbeat) exitHook() {
+	h.hooking.Add(-1)
+}
+
+// Reset makes the Heartbeat reusable: it closes the current context, unless it is done already,
+// and starts over with a new context derived from the parent context, as if the Heartbeat was just created.
+// Ctx() returns the new context afterwards, the contexts cached before stay done.
+// The counters of Stats() are kept. The beats during Reset are ignored like the beats after done.
+// Concurrent Close() and Reset() calls are serialized. Unlike Close(), Reset must not be called from the hooks.
+func (h *Heartbeat) Reset() {
+	h.lifecycle.Lock()
+	defer h.lifecycle.Unlock()
+
+	h.shutdown()
+
+	h.mu.Lock()
+	since := time.Since(h.created)
+	h.lastBeat.Store(int64(since))
+	h.born.Store(int64(since))
+	h.reason.Store(int32(ReasonNone))
+	h.phase.Store(nil)
+	h.label.Store(nil)
+	h.beaten.Store(false)
+	h.notified = -1
+	h.softNotified = -1
+	h.misses = 0
+	if h.schedule != nil {
+		h.schedule.restart(time.Now())
+	}
+	h.gen.Load().closeTicks()
+	h.gen.Store(h.newGeneration(h.own))
+	h.closing.Store(false)
+	prev := State(h.state.Swap(int32(StateRunning)))
+	if h.stateHook != nil && h.enterHook() {
+		h.stateHook(prev, StateRunning)
+		h.exitHook()
+	}
+	h.mu.Unlock()
+
+	if h.restart != nil {
+		h.restart()
+	}
+}
+
+// shutdown closes the current generation and waits for its checks, h.lifecycle must be held.
+func (h *heartbeat) shutdown() {
+	h.close(nil)
+
+	// Wait for the check in progress, the next ones see the context done.
+	h.mu.Lock()
+	if h.suspended {
+		// The goroutine is started again to see it.
+		h.unsuspendLocked()
+	}
+	h.mu.Unlock()
+	if exited := h.gen.Load().exited; exited != nil {
+		<-exited
+	}
 }
 
-func (h *Heartbeat) start() {
-	h.Beat()
+// WatchAlive links the Heartbeat to ctx: once ctx is done, the Heartbeat is closed with the cause of ctx.
+// WatchAlive doesn't beat, the timeout still applies while ctx is alive.
+// The watching goroutine exits when the Heartbeat context is done.
+func (h *Heartbeat) WatchAlive(ctx context.Context) {
+	hb := h.heartbeat
+	g := hb.gen.Load()
+	go func() {
+		select {
+		case <-ctx.Done():
+			// The watch is bound to the current context, it doesn't survive Reset().
+			if hb.gen.Load() == g {
+				hb.close(context.Cause(ctx))
+			}
+		case <-g.ctx.Done():
+		}
+	}()
+}
 
+// CloseOn closes the Heartbeat once done is closed or receives a value, e.g. to tie it to an existing
+// shutdown broadcast channel. The watching goroutine exits when the Heartbeat context is done.
+// Like WatchAlive, it is bound to the current context, it doesn't survive Reset().
+func (h *Heartbeat) CloseOn(done <-chan struct{}) {
+	hb := h.heartbeat
+	g := hb.gen.Load()
 	go func() {
-		ticker := time.NewTicker(h.checkInterval)
-		defer ticker.Stop()
+		select {
+		case <-done:
+			if hb.gen.Load() == g {
+				hb.closeWait()
+			}
+		case <-g.ctx.Done():
+		}
+	}()
+}
+
+func (h *heartbeat) close(cause error) {
+	h.transition(StateClosed)
+	h.cancelCtx(cause)
+}
+
+func (h *heartbeat) start() {
+	h.own = true
+	h.gen.Load().exited = make(chan struct{})
+
+	wakec := make(chan struct{}, 1)
+	h.wake = func() {
+		select {
+		case wakec <- struct{}{}:
+		default:
+		}
+	}
+	h.restart = func() {
+		h.monitor(h.gen.Load(), wakec)
+	}
+
+	h.monitor(h.gen.Load(), wakec)
+}
+
+// monitor starts the goroutine checking the generation g.
+func (h *heartbeat) monitor(g *generation, wakec chan struct{}) {
+	go func() {
+		// A timer rather than a ticker, so that the lateness of every check is known.
+		next := time.Now().Add(h.checkInterval)
+		timer := time.NewTimer(h.checkInterval)
+		defer timer.Stop()
 
 		for {
+			ticked := false
 			select {
-			case <-h.ctx.Done():
+			case <-g.ctx.Done():
+				h.done()
+				close(g.exited)
+				return
+			case <-timer.C:
+				ticked = true
+				h.observeLateness(time.Since(next))
+			case <-wakec:
+			}
+
+			if _, ok := h.check(time.Now()); !ok {
+				h.done()
+				close(g.exited)
 				return
-			case <-ticker.C:
-				last := h.lastBeat.Load()
-				idle := time.Since(*last)
-				left := h.timeout - idle
-
-				if left <= 0 {
-					h.cancelCtx()
-					if h.cancelHook != nil {
-						h.cancelHook(h.timeout, idle, left)
-					}
-					return
+			}
+			if ticked {
+				// The checks missed by a slow check are skipped, not queued.
+				next = next.Add(h.checkInterval)
+				if now := time.Now(); !next.After(now) {
+					next = now.Add(h.checkInterval)
 				}
+				timer.Reset(time.Until(next))
+			}
+			if h.lazy && h.suspend(g) {
+				// The goroutine is started again by unsuspendLocked.
+				return
+			}
+		}
+	}()
+}
+
+// suspend suspends the Heartbeat if the idle timeout has passed, see Options.SuspendWhenIdle.
+// It reports whether the checking goroutine has to exit.
+func (h *heartbeat) suspend(g *generation) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if State(h.state.Load()) != StateRunning || g.ctx.Err() != nil || h.paused.Load() {
+		return false
+	}
+
+	// Set before the expiry is verified, so that a beat landing afterwards wakes the Heartbeat up.
+	h.dormant.Store(true)
+	now := time.Now()
+	if left, reason := h.expiry(now, h.idle(now)); left > 0 || reason != ReasonIdle {
+		h.dormant.Store(false)
+		return false
+	}
+
+	h.suspended = true
+	h.unsuspend = context.AfterFunc(g.ctx, h.wakeUp)
+	return true
+}
+
+// wakeUp starts checking the suspended Heartbeat again.
+func (h *heartbeat) wakeUp() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.suspended {
+		h.unsuspendLocked()
+	}
+}
+
+// unsuspendLocked starts the checking goroutine of the suspended Heartbeat, h.mu must be held.
+func (h *heartbeat) unsuspendLocked() {
+	h.suspended = false
+	h.dormant.Store(false)
+	h.unsuspend()
+	h.unsuspend = nil
+	h.restart()
+}
+
+// Suspended reports whether the Heartbeat is suspended, see Options.SuspendWhenIdle.
+func (h *Heartbeat) Suspended() bool {
+	return h.dormant.Load()
+}
+
+// NextCheckIn returns the approximate time until the next periodic check, e.g. to align logging with the checks.
+// It is estimated from the time of the last check and the check interval, zero once the Heartbeat is done.
+func (h *Heartbeat) NextCheckIn() time.Duration {
+	if h.State() != StateRunning {
+		return 0
+	}
+
+	last := max(h.checkAt.Load(), h.born.Load())
+	next := time.Duration(last) + h.checkInterval
+	return max(next-time.Since(h.created), 0)
+}
 
-				if h.checkHook != nil {
-					h.checkHook(h.timeout, idle, left)
+// CheckResult is the outcome of a timeout check, see CheckNow.
+type CheckResult struct {
+	// At is the time of the check.
+	At time.Time
+	// Timeout, Idle and Left are the same as received by the check hooks.
+	Timeout time.Duration
+	Idle    time.Duration
+	Left    time.Duration
+	// Expired reports whether the check expired the Heartbeat, for Reason.
+	Expired bool
+	Reason  CancelReason
+	// LastBeatLabel is the label of the last labeled beat, see BeatLabeled.
+	LastBeatLabel string
+	// NextSlot is the time of the next slot expecting a beat with Options.Schedule, see NextSlot.
+	NextSlot time.Time
+}
+
+// CheckNow performs a timeout check right away in the calling goroutine, the same as the periodic checks do:
+// it calls the check hooks, or expires the Heartbeat if the timeout has passed. It is serialized with
+// the periodic checks. It reports whether the Heartbeat is still running, the result is zero
+// if it was already done.
+func (h *Heartbeat) CheckNow() (CheckResult, bool) {
+	return h.check(time.Now())
+}
+
+// TickC returns a channel receiving the result of every check, e.g. to react to the checks
+// in the select of a custom loop without hooks. The sends don't block: the results are dropped
+// while the receiver is behind, except for the result of the last check. The channel is closed once the context is done, a new one is returned
+// after Reset().
+func (h *Heartbeat) TickC() <-chan CheckResult {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	g := h.gen.Load()
+	if g.ctx.Err() != nil && g.tickc == nil {
+		c := make(chan CheckResult)
+		close(c)
+		return c
+	}
+	if g.tickc == nil {
+		g.tickc = make(chan CheckResult, 1)
+	}
+	return g.tickc
+}
+
+// check is a single timeout check, it reports whether the checks should go on.
+func (h *heartbeat) check(now time.Time) (CheckResult, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r, ok := h.evaluate(now)
+	if c := h.gen.Load().tickc; c != nil && !r.At.IsZero() {
+		if !ok {
+			// Make room for the last result.
+			select {
+			case <-c:
+			default:
+			}
+		}
+		select {
+		case c <- r:
+		default:
+		}
+	}
+	return r, ok
+}
+
+// evaluate is the body of check, h.mu must be held.
+func (h *heartbeat) evaluate(now time.Time) (CheckResult, bool) {
+	if h.hctx().Err() != nil {
+		return CheckResult{}, false
+	}
+	h.checks.Add(1)
+	h.checkAt.Store(int64(now.Sub(h.created)))
+
+	if h.guarded {
+		h.armGuard(now)
+		defer h.disarmGuard()
+	}
+	if h.beforeCheck != nil && h.enterHook() {
+		h.beforeCheck()
+		h.exitHook()
+	}
+	if h.slowHook != nil || h.latency != nil || h.afterCheck != nil || h.metrics != nil {
+		defer h.checked(time.Now())
+	}
+
+	if h.timeoutFunc != nil {
+		timeout := h.scaled(h.timeoutFunc())
+		if timeout <= 0 {
+			timeout = time.Duration(h.timeout.Load())
+		}
+		h.current.Store(int64(timeout))
+	}
+
+	if c := h.counter.Load(); c != nil {
+		if v := c.p.Load(); v != c.seen {
+			c.seen = v
+			h.beatLocked(now)
+		}
+	}
+
+	last := int64(h.lastBeatSince())
+	idle := now.Sub(h.created) - time.Duration(last)
+	if idle < 0 {
+		// A beat landed since now was taken, the hooks are guaranteed non-negative idle.
+		idle = 0
+	}
+	left := h.left(now, idle)
+	r := CheckResult{At: now, Timeout: h.currentTimeout(), Idle: idle, Left: left, LastBeatLabel: h.lastLabel()}
+	if h.metrics != nil {
+		h.metrics.IncCounter(MetricChecks)
+		h.metrics.SetGauge(MetricIdle, idle.Seconds())
+	}
+
+	// Close() may have been called since the check started, it sets the state before cancelling
+	// and then waits for this check. Re-verify right before calling the hooks.
+	if State(h.state.Load()) != StateRunning {
+		return r, false
+	}
+	if h.rateHook != nil {
+		if h.rate.window <= 0 {
+			h.rate.window = h.checkInterval
+		}
+		rate := h.rate.observe(now, h.beats.Load())
+		if h.enterHook() {
+			h.rateHook(rate)
+			h.exitHook()
+		}
+	}
+	if h.paused.Load() {
+		if h.schedule != nil {
+			h.schedule.restart(now)
+		}
+		return r, true
+	}
+
+	if h.schedule != nil {
+		missed := h.schedule.check(now)
+		r.NextSlot = h.schedule.expected()
+		if missed && h.dontCancel {
+			h.gen.Load().subs.fire(eventWarned)
+			h.onCancel(now, idle, left)
+			return r, true
+		}
+		if missed {
+			if h.expire(now, ReasonMissedSlot, nil, idle, left) {
+				r.Expired, r.Reason = true, ReasonMissedSlot
+			}
+			return r, false
+		}
+	}
+	if h.nearMissHook != nil {
+		h.checkNearMiss()
+	}
+	if h.softHook != nil && idle >= r.Timeout && last != h.softNotified {
+		h.softNotified = last
+		if h.enterHook() {
+			h.softHook(r.Timeout, idle, left)
+			h.exitHook()
+		}
+	}
+
+	if remaining, reason := h.expiry(now, idle); remaining <= 0 {
+		if reason == ReasonParentDeadline {
+			// The parent context is being cancelled, it is reported by done().
+			return r, true
+		}
+		if !h.dontCancel {
+			if reason == ReasonIdle && h.lazy {
+				// The checking goroutine suspends the Heartbeat instead.
+				return r, true
+			}
+			if reason == ReasonIdle && h.debounce > 1 {
+				if last != h.missedBeat {
+					h.missedBeat, h.misses = last, 0
+				}
+				if h.misses++; h.misses < h.debounce {
+					h.onCheck(now, idle, left)
+					return r, true
 				}
 			}
+			if h.expire(now, reason, nil, idle, left) {
+				r.Expired, r.Reason = true, reason
+				return r, false
+			}
+			if State(h.state.Load()) != StateRunning {
+				return r, false
+			}
+			// Beaten since the load, the check goes on.
+		} else if last != h.notified {
+			h.notified = last
+			h.gen.Load().subs.fire(eventWarned)
+			h.onCancel(now, idle, left)
+			return r, true
 		}
+	}
+
+	h.onCheck(now, idle, left)
+	return r, true
+}
+
+// checkNearMiss calls the near-miss hook if a near-miss episode started since the previous check.
+// The episode ends with a beat that isn't a near-miss.
+func (h *heartbeat) checkNearMiss() {
+	// Load the near-misses first, so that the beats counted include them.
+	misses := h.nearMisses.Load()
+	beats := h.beats.Load()
+	defer func() {
+		h.nearMissSeen, h.beatsSeen = misses, beats
 	}()
+
+	if misses == h.nearMissSeen {
+		if beats != h.beatsSeen {
+			h.nearMissEpisode = false
+		}
+		return
+	}
+	if !h.nearMissEpisode {
+		h.nearMissEpisode = true
+		if h.enterHook() {
+			h.nearMissHook(time.Duration(h.nearMissLeft.Load()))
+			h.exitHook()
+		}
+	}
+}
+
+// armGuard arms the timer expiring the Heartbeat on time while the check runs the user functions, so that
+// a slow hook can't delay the cancellation past the timeout. The expiry is then reported from the goroutine
+// of the timer, concurrently with the hook. The timer starts no goroutine unless it fires.
+func (h *heartbeat) armGuard(now time.Time) {
+	left, _ := h.expiry(now, h.idle(now))
+
+	h.guardMu.Lock()
+	defer h.guardMu.Unlock()
+
+	h.guardOn = true
+	if h.guardTimer == nil {
+		h.guardTimer = time.AfterFunc(left, h.guardFired)
+		return
+	}
+	h.guardTimer.Reset(left)
+}
+
+// disarmGuard stops the timer armed by armGuard and waits for the expiry in progress, if any.
+func (h *heartbeat) disarmGuard() {
+	h.guardMu.Lock()
+	defer h.guardMu.Unlock()
+
+	h.guardOn = false
+	h.guardTimer.Stop()
+}
+
+// guardFired expires the Heartbeat once the timer armed by armGuard fires, unless it was beaten meanwhile.
+func (h *heartbeat) guardFired() {
+	h.guardMu.Lock()
+	defer h.guardMu.Unlock()
+
+	// The timer may fire after the check has disarmed it.
+	if !h.guardOn || State(h.state.Load()) != StateRunning {
+		return
+	}
+
+	now := time.Now()
+	idle := h.idle(now)
+	left, reason := h.expiry(now, idle)
+	if left > 0 {
+		h.guardTimer.Reset(left)
+		return
+	}
+	// The parent context is being cancelled by itself.
+	// The checks count the misses of the idle timeout, or suspend the Heartbeat.
+	if reason != ReasonParentDeadline && !h.paused.Load() &&
+		(reason != ReasonIdle || (h.debounce <= 1 && !h.lazy)) {
+		h.expire(now, reason, nil, idle, h.left(now, idle))
+	}
+}
+
+// checked records the duration of the check started at start, calls the slow hook if it took longer
+// than the check interval and then the after check hook.
+func (h *heartbeat) checked(start time.Time) {
+	elapsed := time.Since(start)
+	if h.latency != nil {
+		h.latency.observe(elapsed)
+	}
+	if h.metrics != nil {
+		h.metrics.ObserveDuration(MetricCheckDuration, elapsed)
+	}
+	if overrun := elapsed - h.checkInterval; overrun > 0 && h.slowHook != nil && h.enterHook() {
+		h.slowHook(overrun)
+		h.exitHook()
+	}
+	if h.afterCheck != nil && h.enterHook() {
+		h.afterCheck(elapsed)
+		h.exitHook()
+	}
+}
+
+// observeLateness records the lateness of a periodic check and calls the starvation hook.
+func (h *heartbeat) observeLateness(late time.Duration) {
+	if late < 0 {
+		late = 0
+	}
+	h.lateSum.Add(int64(late))
+	h.lateCount.Add(1)
+	for {
+		max := h.lateMax.Load()
+		if int64(late) <= max || h.lateMax.CompareAndSwap(max, int64(late)) {
+			break
+		}
+	}
+
+	threshold := h.starveAfter
+	if threshold <= 0 {
+		threshold = h.checkInterval
+	}
+	if late > threshold && h.starveHook != nil && h.enterHook() {
+		h.starveHook(late)
+		h.exitHook()
+	}
+}
+
+// done is called once the context is done, it records the terminal state if the parent context was cancelled.
+func (h *heartbeat) done() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// The Heartbeat may have been reset meanwhile.
+	if h.hctx().Err() == nil {
+		return
+	}
+	defer h.gen.Load().closeTicks()
+
+	now := time.Now()
+	idle := h.idle(now)
+
+	// The parent deadline is one of the constraints, report it like the others.
+	if errors.Is(context.Cause(h.hctx()), context.DeadlineExceeded) {
+		if _, reason := h.expiry(now, idle); reason == ReasonParentDeadline {
+			h.expire(now, reason, nil, idle, h.left(now, idle))
+		}
+	}
+
+	// Close() and the expiry set the state before cancelling, so a running Heartbeat has been cancelled by the parent.
+	if h.transition(StateCancelled) && h.parentHook != nil && h.enterHook() {
+		h.parentHook(h.currentTimeout(), idle, h.left(now, idle))
+		h.exitHook()
+	}
+}
+
+// left returns the time left until the timeout passes, clamped to the parent deadline with Options.ClampToParent.
+func (h *heartbeat) left(now time.Time, idle time.Duration) time.Duration {
+	left := h.currentTimeout() - idle
+	if h.clampToParent {
+		if deadline, ok := h.parent.Deadline(); ok {
+			if l := deadline.Sub(now); l < left {
+				left = l
+			}
+		}
+	}
+	return left
+}
+
+// currentTimeout returns the timeout in effect.
+func (h *heartbeat) currentTimeout() time.Duration {
+	return time.Duration(h.current.Load())
+}
+
+// idle returns the time passed since the last beat, zero if it landed after now.
+func (h *heartbeat) idle(now time.Time) time.Duration {
+	if idle := now.Sub(h.created) - h.lastBeatSince(); idle > 0 {
+		return idle
+	}
+	return 0
+}
+
+// lastBeatTime returns the time of the last beat.
+func (h *heartbeat) lastBeatTime() time.Time {
+	return h.created.Add(h.lastBeatSince())
+}
+
+// lastBeatSince returns the time of the last beat since created, including the beats of the parent.
+func (h *heartbeat) lastBeatSince() time.Duration {
+	last := time.Duration(h.lastBeat.Load())
+	if p := h.strictOf; p != nil {
+		last = max(last, p.lastBeatTime().Sub(h.created))
+	}
+	return last
+}
+
+// expiry is the single evaluation point of the cancellation constraints: the idle timeout, the max lifetime,
+// the first beat and the parent deadline. It returns the time left until the earliest of them and its reason.
+// Once several have passed, the one passed first wins.
+func (h *heartbeat) expiry(now time.Time, idle time.Duration) (time.Duration, CancelReason) {
+	left, reason := h.currentTimeout()+h.escalation-idle, ReasonIdle
+
+	age := now.Sub(h.created) - time.Duration(h.born.Load())
+	if h.maxLifetime > 0 {
+		if l := h.maxLifetime - age; l < left {
+			left, reason = l, ReasonMaxLifetime
+		}
+	}
+	if h.firstBeat > 0 && !h.beaten.Load() {
+		if l := h.firstBeat - age; l < left {
+			left, reason = l, ReasonNoFirstBeat
+		}
+	}
+
+	if deadline, ok := h.parent.Deadline(); ok {
+		if l := deadline.Sub(now); l < left {
+			left, reason = l, ReasonParentDeadline
+		}
+	}
+
+	return left, reason
+}
+
+// transition moves a running Heartbeat to the given terminal state and notifies the state hook and the Subscriptions.
+// It reports whether the Heartbeat was running.
+func (h *heartbeat) transition(to State) bool {
+	if !h.state.CompareAndSwap(int32(StateRunning), int32(to)) {
+		return false
+	}
+	if h.stateHook != nil && h.enterHook() {
+		h.stateHook(StateRunning, to)
+		h.exitHook()
+	}
+	if to == StateExpired {
+		h.gen.Load().subs.fire(eventExpired)
+	} else {
+		h.gen.Load().subs.fire(eventClosed)
+	}
+	return true
+}
+
+// expire cancels the context for the given reason and calls the cancel hook, unless the Heartbeat is already done
+// or it was beaten since idle was measured. err is the error wrapped by the cause, see ForceExpire.
+// It reports whether it expired the Heartbeat.
+// The state transition guards the terminal path: whatever triggers the expiry concurrently,
+// the cancel hooks are called at most once.
+func (h *heartbeat) expire(now time.Time, reason CancelReason, err error, idle, left time.Duration) bool {
+	if State(h.state.Load()) != StateRunning {
+		return false
+	}
+
+	if err == nil && h.explain != nil {
+		err = h.explain(now)
+	}
+	cause := &TimeoutError{Name: h.name, Reason: reason, Timeout: h.currentTimeout(), Idle: idle, Err: err}
+	if p := h.phase.Load(); p != nil {
+		cause.Phase, cause.PhaseDuration = p.name, now.Sub(p.since)
+	}
+	cause.LastBeatLabel = h.lastLabel()
+	if reason == ReasonMissedSlot {
+		cause.Slot = h.schedule.at(h.schedule.last.Load())
+	}
+
+	// Honor a beat landed since the expiry was decided, the operation has made progress within the timeout.
+	if (reason == ReasonIdle && h.idle(now) < idle) || (reason == ReasonNoFirstBeat && h.beaten.Load()) {
+		return false
+	}
+
+	// The cause is recorded before the transition, so that it is there once the expiry is observed,
+	// see AfterTimeout. The first expiry claims it, it may still lose the transition to Close().
+	if !h.gen.Load().timeoutErr.CompareAndSwap(nil, cause) || !h.transition(StateExpired) {
+		return false
+	}
+	h.reason.Store(int32(reason))
+	if h.metrics != nil {
+		h.metrics.IncCounter(MetricTimeouts)
+	}
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			h.cancelCtx(cause)
+			if h.cancelFunc != nil {
+				h.cancelFunc(cause)
+			}
+		})
+	}
+
+	if !h.hookFirst {
+		cancel()
+		h.onCancel(now, idle, left)
+		return true
+	}
+
+	guard := time.AfterFunc(h.hookTimeout, cancel)
+	h.onCancel(now, idle, left)
+	guard.Stop()
+	cancel()
+	return true
+}
+
+func (h *heartbeat) onCheck(now time.Time, idle, left time.Duration) {
+	if h.checkHook != nil && h.enterHook() {
+		h.checkHook(h.currentTimeout(), idle, left)
+		h.exitHook()
+	}
+	if h.checkHookAt != nil && h.enterHook() {
+		h.checkHookAt(now, h.currentTimeout(), idle, left)
+		h.exitHook()
+	}
+}
+
+func (h *heartbeat) onCancel(now time.Time, idle, left time.Duration) {
+	if h.cancelHook != nil && h.enterHook() {
+		h.cancelHook(h.currentTimeout(), idle, left)
+		h.exitHook()
+	}
+	if h.cancelHookAt != nil && h.enterHook() {
+		h.cancelHookAt(now, h.currentTimeout(), idle, left)
+		h.exitHook()
+	}
 }
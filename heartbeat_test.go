@@ -2,8 +2,14 @@ package heartbeat_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"math"
+	"runtime"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -23,6 +29,49 @@ func TestNew(t *testing.T) {
 	})
 }
 
+func TestNewE(t *testing.T) {
+	t.Parallel()
+
+	h, err := heartbeat.NewE(context.Background(), time.Second, nil)
+	require.NoError(t, err)
+	defer h.Close()
+	require.NoError(t, h.Ctx().Err())
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		timeout time.Duration
+		opts    *heartbeat.Options
+		err     string
+	}{
+		{"nil context", nil, time.Second, nil, "heartbeat: nil context"},
+		{"zero timeout", context.Background(), 0, nil, "heartbeat: non-positive timeout 0s"},
+		{"negative timeout", context.Background(), -time.Second, nil, "heartbeat: non-positive timeout -1s"},
+		{
+			"invalid options", context.Background(), time.Second,
+			&heartbeat.Options{CheckInterval: -time.Second}, "heartbeat: negative CheckInterval -1s",
+		},
+		{
+			"conflicting options", context.Background(), time.Second,
+			&heartbeat.Options{SuspendWhenIdle: true, DontCancel: true}, "heartbeat: SuspendWhenIdle conflicts with DontCancel",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := heartbeat.NewE(tt.ctx, tt.timeout, tt.opts)
+			require.EqualError(t, err, tt.err)
+			require.Nil(t, h)
+			if tt.opts == nil {
+				// New ignores the invalid options, see TestOptions_Validate.
+				require.PanicsWithError(t, tt.err, func() {
+					heartbeat.New(tt.ctx, tt.timeout, tt.opts)
+				})
+			}
+		})
+	}
+}
+
 func TestHeartbeat(t *testing.T) {
 	t.Parallel()
 
@@ -41,6 +90,8 @@ func TestHeartbeat(t *testing.T) {
 		default:
 			t.Fatal("context is not cancelled")
 		}
+		require.ErrorIs(t, context.Cause(h.Ctx()), heartbeat.ErrTimeout)
+		require.Equal(t, heartbeat.StateExpired, h.State())
 	})
 
 	t.Run("timeout, cancel hook", func(t *testing.T) {
@@ -66,6 +117,88 @@ func TestHeartbeat(t *testing.T) {
 		require.True(t, cancelHookCalled)
 	})
 
+	t.Run("cancel hook ordering", func(t *testing.T) {
+		t.Parallel()
+
+		for _, before := range []bool{false, true} {
+			var hp atomic.Pointer[heartbeat.Heartbeat]
+			hookErr := make(chan error, 1)
+
+			h := heartbeat.New(context.Background(), 50*time.Millisecond, &heartbeat.Options{
+				CheckInterval:          10 * time.Millisecond,
+				CancelHookBeforeCancel: before,
+				CancelHook: func(_, _, _ time.Duration) {
+					hookErr <- hp.Load().Ctx().Err()
+				},
+			})
+			hp.Store(h)
+
+			if before {
+				require.NoError(t, <-hookErr)
+			} else {
+				require.ErrorIs(t, <-hookErr, context.Canceled)
+			}
+			<-h.Ctx().Done()
+			require.ErrorIs(t, context.Cause(h.Ctx()), heartbeat.ErrTimeout)
+			h.Close()
+		}
+	})
+
+	t.Run("cancel hook before cancel, bounded", func(t *testing.T) {
+		t.Parallel()
+
+		release := make(chan struct{})
+		expired := make(chan time.Time, 1)
+		h := heartbeat.New(context.Background(), 50*time.Millisecond, &heartbeat.Options{
+			CheckInterval:          10 * time.Millisecond,
+			CancelHookBeforeCancel: true,
+			CancelHookTimeout:      50 * time.Millisecond,
+			CancelHook: func(_, _, _ time.Duration) {
+				expired <- time.Now()
+				<-release
+			},
+		})
+
+		start := <-expired
+		<-h.Ctx().Done()
+		require.InDelta(t, 50*time.Millisecond, time.Since(start), float64(30*time.Millisecond))
+		require.ErrorIs(t, context.Cause(h.Ctx()), heartbeat.ErrTimeout)
+
+		close(release)
+		h.Close()
+	})
+
+	t.Run("cancel func", func(t *testing.T) {
+		t.Parallel()
+
+		external, cancel := context.WithCancelCause(context.Background())
+		defer cancel(nil)
+
+		var calls atomic.Int64
+		h := heartbeat.New(context.Background(), 50*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+			CancelFunc: func(cause error) {
+				calls.Add(1)
+				cancel(cause)
+			},
+		})
+		defer h.Close()
+
+		<-external.Done()
+		require.ErrorIs(t, context.Cause(external), heartbeat.ErrTimeout)
+		require.ErrorIs(t, context.Cause(h.Ctx()), heartbeat.ErrTimeout)
+		h.Close()
+		require.Equal(t, int64(1), calls.Load())
+
+		closed := heartbeat.New(context.Background(), time.Minute, &heartbeat.Options{
+			CancelFunc: func(error) {
+				calls.Add(1)
+			},
+		})
+		closed.Close()
+		require.Equal(t, int64(1), calls.Load())
+	})
+
 	t.Run("beat before timeout", func(t *testing.T) {
 		t.Parallel()
 
@@ -109,40 +242,1994 @@ func TestHeartbeat(t *testing.T) {
 
 		require.GreaterOrEqual(t, hookCount.Load(), int64(14))
 	})
-}
 
-func TestHeartbeat_Close(t *testing.T) {
-	t.Parallel()
+	t.Run("hooks with time", func(t *testing.T) {
+		t.Parallel()
 
-	t.Run("context is cancelled on close", func(t *testing.T) {
-		h := heartbeat.New(context.Background(), time.Second, &heartbeat.Options{
-			CheckInterval: 100 * time.Millisecond,
+		var checks, cancels atomic.Int64
+
+		h := heartbeat.New(context.Background(), 200*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 50 * time.Millisecond,
+			CheckHook: func(_, _, _ time.Duration) {
+				checks.Add(1)
+			},
+			CheckHookAt: func(now time.Time, timeout, idle, left time.Duration) {
+				checks.Add(1)
+				assert.WithinDuration(t, time.Now(), now, 10*time.Millisecond)
+				assert.Equal(t, 200*time.Millisecond, timeout)
+				assert.Equal(t, idle+left, timeout)
+			},
+			CancelHookAt: func(now time.Time, timeout, idle, left time.Duration) {
+				cancels.Add(1)
+				assert.WithinDuration(t, time.Now(), now, 10*time.Millisecond)
+				assert.LessOrEqual(t, left, time.Duration(0))
+			},
 		})
-		h.Close()
+		defer h.Close()
+
+		<-h.Ctx().Done()
+		require.Eventually(t, func() bool { return cancels.Load() == 1 }, time.Second, 5*time.Millisecond)
+		// Both check hooks are called on every check.
+		require.GreaterOrEqual(t, checks.Load(), int64(4))
+		require.Zero(t, checks.Load()%2)
+	})
+
+	t.Run("dont cancel, cancel hook once per stall", func(t *testing.T) {
+		t.Parallel()
+
+		var hookCount atomic.Int64
+
+		h := heartbeat.New(context.Background(), 200*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 20 * time.Millisecond,
+			DontCancel:    true,
+			CancelHook: func(timeout, idle, left time.Duration) {
+				hookCount.Add(1)
+				assert.LessOrEqual(t, left, time.Duration(0))
+			},
+		})
+		defer h.Close()
+
+		time.Sleep(500 * time.Millisecond)
+		require.Equal(t, int64(1), hookCount.Load())
+		require.NoError(t, h.Ctx().Err())
+
+		h.Beat()
+		time.Sleep(500 * time.Millisecond)
+		require.Equal(t, int64(2), hookCount.Load())
+		require.NoError(t, h.Ctx().Err())
+	})
+
+	t.Run("dont cancel with check hook", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), 50*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+			DontCancel:    true,
+			CheckHook:     func(_, _, _ time.Duration) {},
+		})
+		defer h.Close()
+
+		time.Sleep(150 * time.Millisecond)
+		require.NoError(t, h.Ctx().Err())
+	})
+
+	t.Run("timeout func", func(t *testing.T) {
+		t.Parallel()
+
+		var timeout atomic.Int64
+		timeout.Store(int64(time.Second))
 
+		h := heartbeat.New(context.Background(), 100*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+			TimeoutFunc: func() time.Duration {
+				return time.Duration(timeout.Load())
+			},
+		})
+		defer h.Close()
+
+		time.Sleep(200 * time.Millisecond)
+		require.NoError(t, h.Ctx().Err())
+		require.Equal(t, time.Second, h.Stats().Timeout)
+
+		// Non-positive falls back to the static timeout.
+		timeout.Store(0)
 		select {
 		case <-h.Ctx().Done():
-		default:
-			t.Fatal("context is not cancelled")
+		case <-time.After(time.Second):
+			t.Fatal("not expired")
 		}
+
+		var terr *heartbeat.TimeoutError
+		require.ErrorAs(t, context.Cause(h.Ctx()), &terr)
+		require.Equal(t, 100*time.Millisecond, terr.Timeout)
 	})
 
-	t.Run("no checks after close", func(t *testing.T) {
+	t.Run("before and after check hooks", func(t *testing.T) {
 		t.Parallel()
 
-		var hookCount atomic.Int64
+		var mu sync.Mutex
+		var calls []string
+		record := func(call string) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, call)
+		}
+
+		h := heartbeat.New(context.Background(), 100*time.Millisecond, &heartbeat.Options{
+			CheckInterval:   40 * time.Millisecond,
+			BeforeCheckHook: func() { record("before") },
+			AfterCheckHook: func(duration time.Duration) {
+				assert.Positive(t, duration)
+				record("after")
+			},
+			CancelHook: func(_, _, _ time.Duration) { record("cancel") },
+		})
+		defer h.Close()
+
+		<-h.Ctx().Done()
+		h.Close()
+
+		// No CheckHook, the last check expires.
+		mu.Lock()
+		defer mu.Unlock()
+		require.Equal(t, []string{"before", "after", "before", "after", "before", "cancel", "after"}, calls)
+	})
+
+	t.Run("slow check hook doesn't delay expiry", func(t *testing.T) {
+		t.Parallel()
+
+		start := time.Now()
+		h := heartbeat.New(context.Background(), 100*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+			CheckHook: func(_, _, _ time.Duration) {
+				time.Sleep(200 * time.Millisecond)
+			},
+		})
+		defer h.Close()
+
+		<-h.Ctx().Done()
+		require.InDelta(t, 100*time.Millisecond, time.Since(start), float64(30*time.Millisecond))
+		require.ErrorIs(t, context.Cause(h.Ctx()), heartbeat.ErrTimeout)
+		require.Equal(t, heartbeat.ReasonIdle, h.CancelReason())
+	})
 
+	t.Run("slow hook", func(t *testing.T) {
+		t.Parallel()
+
+		var checks atomic.Int64
+		overruns := make(chan time.Duration, 10)
 		h := heartbeat.New(context.Background(), time.Second, &heartbeat.Options{
-			CheckInterval: 50 * time.Millisecond,
+			CheckInterval: 10 * time.Millisecond,
 			CheckHook: func(_, _, _ time.Duration) {
-				hookCount.Add(1)
+				if checks.Add(1) == 2 {
+					time.Sleep(50 * time.Millisecond)
+				}
+			},
+			SlowHook: func(overrun time.Duration) {
+				overruns <- overrun
 			},
 		})
+		defer h.Close()
+
+		time.Sleep(150 * time.Millisecond)
 		h.Close()
-		hookCount.Store(0)
+		close(overruns)
 
-		time.Sleep(time.Second)
+		var got []time.Duration
+		for overrun := range overruns {
+			got = append(got, overrun)
+		}
+		require.Len(t, got, 1)
+		require.InDelta(t, 40*time.Millisecond, got[0], float64(20*time.Millisecond))
+	})
 
-		require.Equal(t, int64(0), hookCount.Load())
+	t.Run("cancel hook once on concurrent expiry", func(t *testing.T) {
+		t.Parallel()
+
+		for i := 0; i < 50; i++ {
+			var hookCount atomic.Int64
+			h := heartbeat.New(context.Background(), 10*time.Millisecond, &heartbeat.Options{
+				CheckInterval: time.Millisecond,
+				CancelHook: func(_, _, _ time.Duration) {
+					hookCount.Add(1)
+				},
+			})
+
+			// Race the expiry by the checks.
+			time.Sleep(9 * time.Millisecond)
+			var wg sync.WaitGroup
+			for j := 0; j < 4; j++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					h.Expire()
+				}()
+			}
+			wg.Wait()
+			<-h.Ctx().Done()
+			time.Sleep(5 * time.Millisecond)
+			h.Close()
+
+			require.Equal(t, int64(1), hookCount.Load())
+		}
+	})
+}
+
+func TestHeartbeat_nil(t *testing.T) {
+	t.Parallel()
+
+	var h *heartbeat.Heartbeat
+	require.NotPanics(t, h.Beat)
+	require.Equal(t, time.Duration(math.MaxInt64), h.Remaining())
+	require.Equal(t, context.Background(), h.Ctx())
+	require.NotPanics(t, h.Close)
+
+	var beater heartbeat.Beater = h
+	beater.Beat()
+	require.NoError(t, beater.Ctx().Err())
+}
+
+func TestHeartbeat_Parent(t *testing.T) {
+	t.Parallel()
+
+	type key struct{}
+	parent := context.WithValue(context.Background(), key{}, "value")
+	h := heartbeat.New(parent, 50*time.Millisecond, nil)
+	defer h.Close()
+
+	require.Equal(t, parent, h.Parent())
+	<-h.Ctx().Done()
+	require.NoError(t, h.Parent().Err())
+	require.Equal(t, "value", h.Parent().Value(key{}))
+}
+
+func TestHeartbeat_CleanupCtx(t *testing.T) {
+	t.Parallel()
+
+	t.Run("grace after expiry", func(t *testing.T) {
+		t.Parallel()
+
+		type key struct{}
+		parent, cancel := context.WithCancel(context.WithValue(context.Background(), key{}, "value"))
+		defer cancel()
+
+		var hookErr atomic.Value
+		// The hook may run before New returns.
+		var hp atomic.Pointer[heartbeat.Heartbeat]
+		created := make(chan struct{})
+		h := heartbeat.New(parent, 50*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+			CleanupGrace:  100 * time.Millisecond,
+			CancelHook: func(_, _, _ time.Duration) {
+				// The parent is gone too, the cleanup context is not.
+				cancel()
+				<-created
+				hookErr.Store(fmt.Sprint(hp.Load().CleanupCtx().Err()))
+			},
+		})
+		hp.Store(h)
+		close(created)
+		defer h.Close()
+
+		cleanup := h.CleanupCtx()
+		require.Equal(t, "value", cleanup.Value(key{}))
+
+		<-h.Ctx().Done()
+		expired := time.Now()
+		require.Eventually(t, func() bool { return hookErr.Load() != nil }, time.Second, time.Millisecond)
+		require.Equal(t, "<nil>", hookErr.Load())
+
+		<-cleanup.Done()
+		require.InDelta(t, 100*time.Millisecond, time.Since(expired), float64(30*time.Millisecond))
+		require.ErrorIs(t, context.Cause(cleanup), context.DeadlineExceeded)
+	})
+
+	t.Run("without grace", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), time.Minute, nil)
+		require.Equal(t, h.Ctx(), h.CleanupCtx())
+		h.Close()
+		require.Error(t, h.CleanupCtx().Err())
+	})
+}
+
+func TestHeartbeat_Beat(t *testing.T) {
+	t.Parallel()
+
+	t.Run("beat hook", func(t *testing.T) {
+		t.Parallel()
+
+		var idles []time.Duration
+
+		h := heartbeat.New(context.Background(), time.Second, &heartbeat.Options{
+			BeatHook: func(at time.Time, idleBefore time.Duration) {
+				assert.WithinDuration(t, time.Now(), at, 10*time.Millisecond)
+				idles = append(idles, idleBefore)
+			},
+		})
+		defer h.Close()
+
+		require.Empty(t, idles)
+
+		time.Sleep(100 * time.Millisecond)
+		h.Beat()
+		time.Sleep(200 * time.Millisecond)
+		h.Beat()
+
+		require.Len(t, idles, 2)
+		assert.InDelta(t, 100*time.Millisecond, idles[0], float64(20*time.Millisecond))
+		assert.InDelta(t, 200*time.Millisecond, idles[1], float64(20*time.Millisecond))
+	})
+
+	t.Run("near miss", func(t *testing.T) {
+		t.Parallel()
+
+		lefts := make(chan time.Duration, 10)
+		h := heartbeat.New(context.Background(), 200*time.Millisecond, &heartbeat.Options{
+			CheckInterval:    10 * time.Millisecond,
+			NearMissFraction: 0.25,
+			NearMissHook: func(left time.Duration) {
+				lefts <- left
+			},
+		})
+		defer h.Close()
+
+		time.Sleep(50 * time.Millisecond)
+		h.Beat()
+		time.Sleep(170 * time.Millisecond)
+		h.Beat()
+
+		require.InDelta(t, 30*time.Millisecond, <-lefts, float64(20*time.Millisecond))
+		require.Equal(t, uint64(1), h.Stats().NearMissBeats)
+
+		// The hook is called once per episode.
+		time.Sleep(170 * time.Millisecond)
+		h.Beat()
+		time.Sleep(50 * time.Millisecond)
+		require.Equal(t, uint64(2), h.Stats().NearMissBeats)
+		require.Empty(t, lefts)
+
+		// A beat with enough time to spare ends the episode.
+		h.Beat()
+		time.Sleep(180 * time.Millisecond)
+		h.Beat()
+		require.InDelta(t, 20*time.Millisecond, <-lefts, float64(20*time.Millisecond))
+		require.Equal(t, uint64(3), h.Stats().NearMissBeats)
+	})
+
+	t.Run("after done", func(t *testing.T) {
+		t.Parallel()
+
+		var hookCount atomic.Int64
+		h := heartbeat.New(context.Background(), 50*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+			BeatHook: func(time.Time, time.Duration) {
+				hookCount.Add(1)
+			},
+		})
+		defer h.Close()
+
+		<-h.Ctx().Done()
+		remaining := h.Remaining()
+		h.Beat()
+		h.BeatWeight(0.5)
+
+		stats := h.Stats()
+		require.Zero(t, stats.Beats)
+		require.Equal(t, uint64(2), stats.BeatsAfterDone)
+		require.Zero(t, hookCount.Load())
+		require.LessOrEqual(t, h.Remaining(), remaining)
 	})
 }
+
+func TestHeartbeat_Beat_noSpuriousCancel(t *testing.T) {
+	t.Parallel()
+
+	var checks atomic.Int64
+	h := heartbeat.New(context.Background(), 10*time.Millisecond, &heartbeat.Options{
+		CheckInterval: time.Hour,
+		CheckHook: func(_, _, _ time.Duration) {
+			checks.Add(1)
+		},
+	})
+	defer h.Close()
+	// Beat after the check has decided to expire the Heartbeat, before the transition.
+	h.SetExplain(func(time.Time) error {
+		h.Beat()
+		return nil
+	})
+
+	time.Sleep(20 * time.Millisecond)
+	r, ok := h.CheckNow()
+	require.True(t, ok)
+	require.False(t, r.Expired)
+	require.NoError(t, h.Ctx().Err())
+	require.Equal(t, heartbeat.StateRunning, h.State())
+	require.Equal(t, int64(1), checks.Load(), "the check goes on")
+}
+
+func TestHeartbeat_BeatAt(t *testing.T) {
+	t.Parallel()
+
+	t.Run("past", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), time.Minute, nil)
+		defer h.Close()
+
+		at := time.Now().Add(-time.Second)
+		h.BeatAt(at)
+		require.Zero(t, h.Stats().Beats, "before the implicit beat on creation")
+
+		time.Sleep(10 * time.Millisecond)
+		at = time.Now().Add(-5 * time.Millisecond)
+		h.BeatAt(at)
+		require.Equal(t, uint64(1), h.Stats().Beats)
+		require.True(t, h.Stats().LastBeat.Equal(at))
+	})
+
+	t.Run("future is clamped", func(t *testing.T) {
+		t.Parallel()
+
+		var mu sync.Mutex
+		var violations []string
+		h := heartbeat.New(context.Background(), 100*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+			CheckHook: func(timeout, idle, left time.Duration) {
+				if idle < 0 || left > timeout || idle+left != timeout {
+					mu.Lock()
+					violations = append(violations, fmt.Sprint(timeout, idle, left))
+					mu.Unlock()
+				}
+			},
+		})
+		defer h.Close()
+
+		h.BeatAt(time.Now().Add(time.Hour))
+		stats := h.Stats()
+		require.Equal(t, uint64(1), stats.SkewedBeats)
+		require.GreaterOrEqual(t, stats.Idle, time.Duration(0))
+		require.LessOrEqual(t, stats.Remaining, stats.Timeout)
+		require.WithinDuration(t, time.Now(), stats.LastBeat, 10*time.Millisecond)
+
+		// The future beat doesn't extend the deadline.
+		<-h.Ctx().Done()
+		require.ErrorIs(t, context.Cause(h.Ctx()), heartbeat.ErrTimeout)
+		mu.Lock()
+		defer mu.Unlock()
+		require.Empty(t, violations)
+	})
+}
+
+func TestHeartbeat_BeatWeight(t *testing.T) {
+	t.Parallel()
+
+	newHeartbeat := func() *heartbeat.Heartbeat {
+		h := heartbeat.New(context.Background(), time.Second, &heartbeat.Options{
+			CheckInterval: 50 * time.Millisecond,
+			DontCancel:    true,
+		})
+		t.Cleanup(h.Close)
+		return h
+	}
+
+	tests := []struct {
+		weight float64
+		left   time.Duration
+	}{
+		{weight: -1, left: 600 * time.Millisecond},
+		{weight: 0, left: 600 * time.Millisecond},
+		{weight: 0.5, left: 800 * time.Millisecond},
+		{weight: 1, left: time.Second},
+		{weight: 2, left: time.Second},
+	}
+
+	hs := make([]*heartbeat.Heartbeat, len(tests))
+	for i := range tests {
+		hs[i] = newHeartbeat()
+	}
+
+	time.Sleep(400 * time.Millisecond)
+
+	for i, tt := range tests {
+		hs[i].BeatWeight(tt.weight)
+		assert.InDelta(t, tt.left, hs[i].Remaining(), float64(50*time.Millisecond), "weight %v", tt.weight)
+	}
+}
+
+func TestHeartbeat_BeatThrottled(t *testing.T) {
+	t.Parallel()
+
+	h := heartbeat.New(context.Background(), time.Second, nil)
+	defer h.Close()
+
+	require.False(t, h.BeatThrottled(time.Hour))
+	time.Sleep(50 * time.Millisecond)
+	require.False(t, h.BeatThrottled(100*time.Millisecond))
+	require.True(t, h.BeatThrottled(40*time.Millisecond))
+	require.False(t, h.BeatThrottled(40*time.Millisecond))
+	require.True(t, h.BeatThrottled(0))
+	require.Equal(t, uint64(2), h.Stats().Beats)
+	require.InDelta(t, time.Second, h.Remaining(), float64(10*time.Millisecond))
+
+	h.Close()
+	require.False(t, h.BeatThrottled(0))
+}
+
+func TestHeartbeat_UserBeaten(t *testing.T) {
+	t.Parallel()
+
+	h := heartbeat.New(context.Background(), time.Second, nil)
+	defer h.Close()
+	require.False(t, h.UserBeaten())
+
+	h.BeatWeight(0)
+	require.False(t, h.UserBeaten())
+
+	h.Beat()
+	require.True(t, h.UserBeaten())
+
+	weighted := heartbeat.New(context.Background(), time.Second, nil)
+	defer weighted.Close()
+	weighted.BeatWeight(0.5)
+	require.True(t, weighted.UserBeaten())
+}
+
+func TestHeartbeat_SetPhase(t *testing.T) {
+	t.Parallel()
+
+	t.Run("timeout error", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), 100*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+		})
+		defer h.Close()
+		require.Empty(t, h.Phase())
+
+		h.SetPhase("downloading")
+		h.SetPhase("uploading artifacts")
+		require.Equal(t, "uploading artifacts", h.Phase())
+		require.Equal(t, "uploading artifacts", h.Stats().Phase)
+		require.Equal(t, uint64(2), h.Stats().Beats)
+
+		<-h.Ctx().Done()
+		var terr *heartbeat.TimeoutError
+		require.ErrorAs(t, context.Cause(h.Ctx()), &terr)
+		require.Equal(t, "uploading artifacts", terr.Phase)
+		require.GreaterOrEqual(t, terr.PhaseDuration, 100*time.Millisecond)
+		require.Contains(t, terr.Error(), `in phase "uploading artifacts" after `)
+
+		h.Reset()
+		require.Empty(t, h.Phase())
+	})
+
+	t.Run("dont beat", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), time.Second, &heartbeat.Options{DontBeatOnPhase: true})
+		defer h.Close()
+
+		h.SetPhase("uploading artifacts")
+		require.Equal(t, "uploading artifacts", h.Phase())
+		require.Zero(t, h.Stats().Beats)
+	})
+}
+
+func TestHeartbeat_BeatLabeled(t *testing.T) {
+	t.Parallel()
+
+	h := heartbeat.New(context.Background(), 100*time.Millisecond, &heartbeat.Options{
+		CheckInterval: 10 * time.Millisecond,
+	})
+	defer h.Close()
+	require.Empty(t, h.LastBeatLabel())
+
+	h.BeatLabeled("parse")
+	h.BeatLabeled("upload")
+	h.Beat()
+	require.Equal(t, "upload", h.LastBeatLabel(), "Beat keeps the label")
+	require.Equal(t, "upload", h.Stats().LastBeatLabel)
+	require.Equal(t, uint64(3), h.Stats().Beats)
+
+	r, ok := h.CheckNow()
+	require.True(t, ok)
+	require.Equal(t, "upload", r.LastBeatLabel)
+
+	<-h.Ctx().Done()
+	var terr *heartbeat.TimeoutError
+	require.ErrorAs(t, context.Cause(h.Ctx()), &terr)
+	require.Equal(t, "upload", terr.LastBeatLabel)
+	require.Contains(t, terr.Error(), `since beat "upload"`)
+
+	h.Reset()
+	require.Empty(t, h.LastBeatLabel())
+}
+
+func TestHeartbeat_BeatLabeled_noAllocs(t *testing.T) {
+	h := heartbeat.New(context.Background(), time.Minute, nil)
+	defer h.Close()
+
+	label := "upload"
+	require.Zero(t, testing.AllocsPerRun(100, func() {
+		h.BeatLabeled(label)
+	}))
+}
+
+func TestHeartbeat_BindCounter(t *testing.T) {
+	t.Parallel()
+
+	h := heartbeat.New(context.Background(), 100*time.Millisecond, &heartbeat.Options{
+		CheckInterval: 10 * time.Millisecond,
+	})
+	defer h.Close()
+
+	var counter atomic.Uint64
+	counter.Store(42)
+	h.BindCounter(&counter)
+
+	for i := 0; i < 20; i++ {
+		counter.Add(1)
+		time.Sleep(20 * time.Millisecond)
+	}
+	require.NoError(t, h.Ctx().Err())
+	require.GreaterOrEqual(t, h.Stats().Beats, uint64(15))
+
+	// No change since the last check, no beat.
+	<-h.Ctx().Done()
+	require.ErrorIs(t, context.Cause(h.Ctx()), heartbeat.ErrTimeout)
+}
+
+func TestHeartbeat_SetTimeout(t *testing.T) {
+	t.Parallel()
+
+	t.Run("shrink wakes the checks", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), 10*time.Minute, &heartbeat.Options{
+			CheckInterval: 10 * time.Minute,
+		})
+		defer h.Close()
+
+		time.Sleep(100 * time.Millisecond)
+		start := time.Now()
+		h.SetTimeout(50 * time.Millisecond)
+
+		<-h.Ctx().Done()
+		require.Less(t, time.Since(start), 20*time.Millisecond)
+		var terr *heartbeat.TimeoutError
+		require.ErrorAs(t, context.Cause(h.Ctx()), &terr)
+		require.Equal(t, 50*time.Millisecond, terr.Timeout)
+	})
+
+	t.Run("shrink, not passed yet", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), time.Minute, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+		})
+		defer h.Close()
+
+		h.SetTimeout(100 * time.Millisecond)
+		require.Equal(t, 100*time.Millisecond, h.Stats().Timeout)
+		time.Sleep(50 * time.Millisecond)
+		require.NoError(t, h.Ctx().Err())
+		<-h.Ctx().Done()
+	})
+
+	t.Run("extend", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), 50*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+		})
+		defer h.Close()
+
+		h.SetTimeout(time.Second)
+		time.Sleep(100 * time.Millisecond)
+		require.NoError(t, h.Ctx().Err())
+	})
+
+	t.Run("manager", func(t *testing.T) {
+		t.Parallel()
+
+		m := heartbeat.NewManager(10 * time.Minute)
+		defer m.Close()
+		h := m.New(context.Background(), 10*time.Minute, nil)
+		defer h.Close()
+
+		time.Sleep(100 * time.Millisecond)
+		start := time.Now()
+		h.SetTimeout(50 * time.Millisecond)
+
+		<-h.Ctx().Done()
+		require.Less(t, time.Since(start), 20*time.Millisecond)
+	})
+
+	t.Run("non-positive", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), time.Second, nil)
+		defer h.Close()
+		require.Panics(t, func() {
+			h.SetTimeout(0)
+		})
+	})
+}
+
+func TestHeartbeat_Healthy(t *testing.T) {
+	t.Parallel()
+
+	t.Run("timeout passed", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), 100*time.Millisecond, &heartbeat.Options{
+			DontCancel: true,
+		})
+		defer h.Close()
+
+		require.True(t, h.Healthy())
+		time.Sleep(150 * time.Millisecond)
+		require.False(t, h.Healthy())
+		h.Beat()
+		require.True(t, h.Healthy())
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), time.Second, nil)
+		h.Close()
+		require.False(t, h.Healthy())
+		h.Beat()
+		require.False(t, h.Healthy())
+	})
+}
+
+func TestHeartbeat_Remaining(t *testing.T) {
+	t.Parallel()
+
+	h := heartbeat.New(context.Background(), time.Second, &heartbeat.Options{
+		CheckInterval: 50 * time.Millisecond,
+		DontCancel:    true,
+	})
+	defer h.Close()
+
+	require.InDelta(t, time.Second, h.Remaining(), float64(50*time.Millisecond))
+
+	time.Sleep(300 * time.Millisecond)
+	require.InDelta(t, 700*time.Millisecond, h.Remaining(), float64(50*time.Millisecond))
+
+	h.Beat()
+	require.InDelta(t, time.Second, h.Remaining(), float64(50*time.Millisecond))
+}
+
+func TestHeartbeat_Deadline(t *testing.T) {
+	t.Parallel()
+
+	t.Run("last beat plus timeout", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), time.Second, nil)
+		defer h.Close()
+
+		h.Beat()
+		require.WithinDuration(t, time.Now().Add(time.Second), h.Deadline(), 10*time.Millisecond)
+	})
+
+	t.Run("clamp to parent", func(t *testing.T) {
+		t.Parallel()
+
+		parent, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		parentDeadline, _ := parent.Deadline()
+
+		var maxLeft atomic.Int64
+		h := heartbeat.New(parent, time.Second, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+			ClampToParent: true,
+			CheckHook: func(_, _, left time.Duration) {
+				if int64(left) > maxLeft.Load() {
+					maxLeft.Store(int64(left))
+				}
+			},
+		})
+		defer h.Close()
+
+		require.WithinDuration(t, parentDeadline, h.Deadline(), time.Millisecond)
+		require.LessOrEqual(t, h.Remaining(), 200*time.Millisecond)
+		require.LessOrEqual(t, h.Stats().Remaining, 200*time.Millisecond)
+
+		<-h.Ctx().Done()
+		require.LessOrEqual(t, time.Duration(maxLeft.Load()), 200*time.Millisecond)
+		require.Eventually(t, func() bool {
+			return h.CancelReason() == heartbeat.ReasonParentDeadline
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("not clamped by default", func(t *testing.T) {
+		t.Parallel()
+
+		parent, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+
+		h := heartbeat.New(parent, time.Second, nil)
+		defer h.Close()
+
+		require.Greater(t, h.Remaining(), 900*time.Millisecond)
+	})
+}
+
+func TestHeartbeat_Derive(t *testing.T) {
+	t.Parallel()
+
+	h := heartbeat.New(context.Background(), 50*time.Millisecond, &heartbeat.Options{
+		CheckInterval: 10 * time.Millisecond,
+	})
+	defer h.Close()
+
+	first, cancelFirst := h.Derive()
+	defer cancelFirst()
+	second, cancelSecond := h.Derive()
+	cancelSecond()
+	require.Error(t, second.Err())
+	require.NoError(t, first.Err(), "the children are cancelled on their own")
+	require.NoError(t, h.Ctx().Err())
+
+	hb, ok := heartbeat.FromContext(first)
+	require.True(t, ok)
+	require.Same(t, h, hb)
+
+	third, cancelThird := h.Derive()
+	defer cancelThird()
+	<-h.Ctx().Done()
+	for _, ctx := range []context.Context{first, third} {
+		<-ctx.Done()
+		require.ErrorIs(t, context.Cause(ctx), heartbeat.ErrTimeout)
+	}
+}
+
+func TestHeartbeat_WithBudget(t *testing.T) {
+	t.Parallel()
+
+	h := heartbeat.New(context.Background(), 100*time.Millisecond, nil)
+	defer h.Close()
+
+	time.Sleep(50 * time.Millisecond)
+	ctx, cancel := h.WithBudget(context.Background())
+	defer cancel()
+
+	deadline, ok := ctx.Deadline()
+	require.True(t, ok)
+	require.WithinDuration(t, time.Now().Add(50*time.Millisecond), deadline, 20*time.Millisecond)
+
+	// A snapshot, not moved by the beats.
+	h.Beat()
+	<-ctx.Done()
+	require.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+	require.NoError(t, h.Ctx().Err())
+}
+
+func TestHeartbeat_Fraction(t *testing.T) {
+	t.Parallel()
+
+	h := heartbeat.New(context.Background(), 400*time.Millisecond, &heartbeat.Options{
+		CheckInterval: 50 * time.Millisecond,
+		DontCancel:    true,
+	})
+	defer h.Close()
+
+	require.InDelta(t, 1, h.Fraction(), 0.1)
+
+	time.Sleep(200 * time.Millisecond)
+	require.InDelta(t, 0.5, h.Fraction(), 0.1)
+
+	time.Sleep(300 * time.Millisecond)
+	require.Equal(t, float64(0), h.Fraction())
+
+	h.Beat()
+	require.InDelta(t, 1, h.Fraction(), 0.1)
+}
+
+func TestHeartbeat_Close(t *testing.T) {
+	t.Parallel()
+
+	t.Run("context is cancelled on close", func(t *testing.T) {
+		h := heartbeat.New(context.Background(), time.Second, &heartbeat.Options{
+			CheckInterval: 100 * time.Millisecond,
+		})
+		h.Close()
+
+		select {
+		case <-h.Ctx().Done():
+		default:
+			t.Fatal("context is not cancelled")
+		}
+		require.ErrorIs(t, context.Cause(h.Ctx()), context.Canceled)
+	})
+
+	t.Run("finalizer closes", func(t *testing.T) {
+		t.Parallel()
+
+		ctx := func() context.Context {
+			h := heartbeat.New(context.Background(), time.Hour, &heartbeat.Options{
+				SetFinalizer: true,
+			})
+			return h.Ctx()
+		}()
+
+		for i := 0; i < 50 && ctx.Err() == nil; i++ {
+			runtime.GC()
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		require.ErrorIs(t, context.Cause(ctx), context.Canceled)
+	})
+
+	t.Run("no checks after close", func(t *testing.T) {
+		t.Parallel()
+
+		var hookCount atomic.Int64
+
+		h := heartbeat.New(context.Background(), time.Second, &heartbeat.Options{
+			CheckInterval: 50 * time.Millisecond,
+			CheckHook: func(_, _, _ time.Duration) {
+				hookCount.Add(1)
+			},
+		})
+		h.Close()
+		hookCount.Store(0)
+
+		time.Sleep(time.Second)
+
+		require.Equal(t, int64(0), hookCount.Load())
+	})
+
+	t.Run("no hook after close returns", func(t *testing.T) {
+		t.Parallel()
+
+		for i := 0; i < 200; i++ {
+			var closed atomic.Bool
+			var late atomic.Int64
+			hook := func(_, _, _ time.Duration) {
+				if closed.Load() {
+					late.Add(1)
+				}
+			}
+
+			// Let Close race the checks and the expiry.
+			timeout := time.Duration(1+i%5) * time.Millisecond
+			h := heartbeat.New(context.Background(), timeout, &heartbeat.Options{
+				CheckInterval: time.Millisecond,
+				CheckHook:     hook,
+				CancelHook:    hook,
+			})
+			time.Sleep(timeout)
+			h.Close()
+			closed.Store(true)
+
+			time.Sleep(2 * time.Millisecond)
+			require.Zero(t, late.Load())
+		}
+	})
+
+	t.Run("no check hook once closing", func(t *testing.T) {
+		t.Parallel()
+
+		for i := 0; i < 20; i++ {
+			var closing atomic.Bool
+			var late, checks atomic.Int64
+			h := heartbeat.New(context.Background(), time.Minute, &heartbeat.Options{
+				CheckInterval: time.Hour,
+				CheckHook: func(_, _, _ time.Duration) {
+					checks.Add(1)
+					if closing.Load() {
+						late.Add(1)
+					}
+				},
+				StateHook: func(_, to heartbeat.State) {
+					if to == heartbeat.StateClosed {
+						closing.Store(true)
+					}
+				},
+			})
+
+			// Race Close with the checks on demand.
+			started := make(chan struct{})
+			stopped := make(chan struct{})
+			go func() {
+				defer close(stopped)
+				for n := 0; ; n++ {
+					if _, ok := h.CheckNow(); !ok {
+						return
+					}
+					if n == 0 {
+						close(started)
+					}
+				}
+			}()
+			<-started
+			h.Close()
+			after := checks.Load()
+			<-stopped
+
+			// Close doesn't wait for the check hook running meanwhile, if any, the checks are serialized.
+			require.LessOrEqual(t, late.Load(), int64(1))
+			require.LessOrEqual(t, checks.Load()-after, int64(1))
+		}
+	})
+
+	t.Run("waits for the check in progress", func(t *testing.T) {
+		t.Parallel()
+
+		started := make(chan struct{}, 1)
+		var finished atomic.Bool
+
+		h := heartbeat.New(context.Background(), time.Second, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+			TimeoutFunc: func() time.Duration {
+				if finished.Load() {
+					return 0
+				}
+				started <- struct{}{}
+				time.Sleep(50 * time.Millisecond)
+				finished.Store(true)
+				return 0
+			},
+		})
+
+		<-started
+		h.Close()
+		require.True(t, finished.Load())
+	})
+
+	t.Run("no hook after the running hook", func(t *testing.T) {
+		t.Parallel()
+
+		started := make(chan struct{}, 1)
+		release := make(chan struct{})
+		var after atomic.Int64
+
+		h := heartbeat.New(context.Background(), time.Second, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+			CheckHook: func(_, _, _ time.Duration) {
+				select {
+				case started <- struct{}{}:
+					<-release
+				default:
+				}
+			},
+			AfterCheckHook: func(time.Duration) {
+				after.Add(1)
+			},
+		})
+
+		<-started
+		calls := after.Load()
+		// The running hook may be the caller, Close doesn't wait for it.
+		h.Close()
+		require.Error(t, h.Ctx().Err())
+		close(release)
+
+		time.Sleep(50 * time.Millisecond)
+		require.Equal(t, calls, after.Load())
+	})
+
+	t.Run("final check", func(t *testing.T) {
+		t.Parallel()
+
+		var checks, cancels atomic.Int64
+		var lastIdle atomic.Int64
+		opts := &heartbeat.Options{
+			CheckInterval:     time.Hour,
+			FinalCheckOnClose: true,
+			CheckHook: func(_, idle, _ time.Duration) {
+				checks.Add(1)
+				lastIdle.Store(int64(idle))
+			},
+			CancelHook: func(_, _, _ time.Duration) {
+				cancels.Add(1)
+			},
+		}
+
+		h := heartbeat.New(context.Background(), time.Minute, opts)
+		time.Sleep(20 * time.Millisecond)
+		h.Close()
+		h.Close()
+		require.Equal(t, int64(1), checks.Load())
+		require.GreaterOrEqual(t, time.Duration(lastIdle.Load()), 20*time.Millisecond)
+		require.Zero(t, cancels.Load())
+
+		expired := heartbeat.New(context.Background(), time.Minute, opts)
+		require.NoError(t, expired.ForceExpire(nil))
+		expired.Close()
+		require.Equal(t, int64(1), checks.Load())
+		require.Equal(t, int64(1), cancels.Load())
+	})
+}
+
+func TestOptions_ConsecutiveMissesToCancel(t *testing.T) {
+	t.Parallel()
+
+	for _, misses := range []int{2, 3} {
+		misses := misses
+		t.Run(fmt.Sprint(misses), func(t *testing.T) {
+			t.Parallel()
+
+			var checks atomic.Int32
+			opts := &heartbeat.Options{
+				CheckInterval:             50 * time.Millisecond,
+				ConsecutiveMissesToCancel: misses,
+				CheckHook: func(_, _, left time.Duration) {
+					if left <= 0 {
+						checks.Add(1)
+					}
+				},
+			}
+			h := heartbeat.New(context.Background(), 100*time.Millisecond, opts)
+			defer h.Close()
+			start := time.Now()
+
+			<-h.Ctx().Done()
+			require.Equal(t, heartbeat.ReasonIdle, h.CancelReason())
+			require.Equal(t, int32(misses-1), checks.Load())
+			require.InDelta(t, 100*time.Millisecond+time.Duration(misses-1)*50*time.Millisecond, time.Since(start),
+				float64(30*time.Millisecond))
+
+			// A beat starts the count over: the check at 100ms has missed once, the count would reach
+			// the threshold a check before the expiry otherwise.
+			h.Reset()
+			time.Sleep(120 * time.Millisecond)
+			h.Beat()
+			time.Sleep(155*time.Millisecond + time.Duration(misses-2)*50*time.Millisecond)
+			require.NoError(t, h.Ctx().Err())
+			<-h.Ctx().Done()
+		})
+	}
+}
+
+func TestOptions_SuspendWhenIdle(t *testing.T) {
+	t.Parallel()
+
+	opts := &heartbeat.Options{
+		CheckInterval:   10 * time.Millisecond,
+		SuspendWhenIdle: true,
+	}
+	suspended := func(t *testing.T, h *heartbeat.Heartbeat) {
+		t.Helper()
+		require.Eventually(t, h.Suspended, time.Second, 5*time.Millisecond)
+	}
+
+	t.Run("beat", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), 50*time.Millisecond, opts)
+		defer h.Close()
+
+		suspended(t, h)
+		checks := h.Stats().Checks
+		time.Sleep(50 * time.Millisecond)
+		require.Equal(t, checks, h.Stats().Checks, "not checked while suspended")
+		require.NoError(t, h.Ctx().Err())
+		require.Equal(t, heartbeat.StateRunning, h.State())
+
+		h.Beat()
+		require.False(t, h.Suspended())
+		time.Sleep(30 * time.Millisecond)
+		require.Greater(t, h.Stats().Checks, checks)
+		suspended(t, h)
+	})
+
+	t.Run("close", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), 20*time.Millisecond, opts)
+		suspended(t, h)
+		h.Close()
+		require.Equal(t, heartbeat.StateClosed, h.State())
+		require.False(t, h.Suspended())
+	})
+
+	t.Run("parent", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		h := heartbeat.New(ctx, 20*time.Millisecond, opts)
+		defer h.Close()
+		suspended(t, h)
+		cancel()
+		require.Eventually(t, func() bool {
+			return h.State() == heartbeat.StateCancelled
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("force expire", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), 20*time.Millisecond, opts)
+		suspended(t, h)
+		require.NoError(t, h.ForceExpire(nil))
+		h.Close()
+		require.Equal(t, heartbeat.ReasonForced, h.CancelReason())
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), 20*time.Millisecond, opts)
+		defer h.Close()
+		suspended(t, h)
+		h.Reset()
+		require.False(t, h.Suspended())
+		suspended(t, h)
+	})
+
+	t.Run("bound counter", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), 50*time.Millisecond, opts)
+		defer h.Close()
+		var counter atomic.Uint64
+		h.BindCounter(&counter)
+		suspended(t, h)
+
+		counter.Add(1)
+		checked := make(chan struct{})
+		go func() {
+			defer close(checked)
+			h.CheckNow()
+		}()
+		select {
+		case <-checked:
+		case <-time.After(time.Second):
+			t.Fatal("CheckNow deadlocked")
+		}
+		require.False(t, h.Suspended())
+		require.Greater(t, h.Remaining(), 30*time.Millisecond)
+		suspended(t, h)
+	})
+}
+
+func TestHeartbeat_Ctx_identity(t *testing.T) {
+	t.Parallel()
+
+	h := heartbeat.New(context.Background(), time.Minute, nil)
+	defer h.Close()
+
+	ctx := h.Ctx()
+	require.True(t, ctx == h.Ctx())
+	h.Beat()
+	require.True(t, ctx == h.Ctx())
+
+	h.Reset()
+	require.False(t, ctx == h.Ctx())
+	require.Error(t, ctx.Err())
+	require.True(t, h.Ctx() == h.Ctx())
+
+	h.Close()
+	require.True(t, h.Ctx() == h.Ctx())
+}
+
+func TestHeartbeat_Reset(t *testing.T) {
+	t.Parallel()
+
+	opts := &heartbeat.Options{CheckInterval: 10 * time.Millisecond}
+
+	t.Run("reuse after expiry", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), 50*time.Millisecond, opts)
+		defer h.Close()
+
+		expired := h.Ctx()
+		<-expired.Done()
+		require.Equal(t, heartbeat.StateExpired, h.State())
+
+		h.Reset()
+		require.ErrorIs(t, context.Cause(expired), heartbeat.ErrTimeout)
+		require.NoError(t, h.Ctx().Err())
+		require.Equal(t, heartbeat.StateRunning, h.State())
+		require.Equal(t, heartbeat.ReasonNone, h.CancelReason())
+		require.Greater(t, h.Remaining(), 40*time.Millisecond)
+		got, ok := heartbeat.FromContext(h.Ctx())
+		require.True(t, ok)
+		require.Same(t, h, got)
+
+		<-h.Ctx().Done()
+		require.ErrorIs(t, context.Cause(h.Ctx()), heartbeat.ErrTimeout)
+	})
+
+	t.Run("reset running", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), time.Minute, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+			MaxLifetime:   200 * time.Millisecond,
+		})
+		defer h.Close()
+
+		old := h.Ctx()
+		time.Sleep(150 * time.Millisecond)
+		h.Reset()
+		require.ErrorIs(t, context.Cause(old), context.Canceled)
+
+		// The lifetime starts over.
+		time.Sleep(100 * time.Millisecond)
+		require.NoError(t, h.Ctx().Err())
+		<-h.Ctx().Done()
+		require.Equal(t, heartbeat.ReasonMaxLifetime, h.CancelReason())
+	})
+
+	t.Run("manager", func(t *testing.T) {
+		t.Parallel()
+
+		m := heartbeat.NewManager(10 * time.Millisecond)
+		defer m.Close()
+		h := m.New(context.Background(), 50*time.Millisecond, nil)
+		defer h.Close()
+
+		<-h.Ctx().Done()
+		require.Eventually(t, func() bool { return m.Len() == 0 }, time.Second, 5*time.Millisecond)
+
+		h.Reset()
+		require.Equal(t, 1, m.Len())
+		<-h.Ctx().Done()
+		require.ErrorIs(t, context.Cause(h.Ctx()), heartbeat.ErrTimeout)
+	})
+
+	t.Run("concurrent beat, close and reset", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), 5*time.Millisecond, &heartbeat.Options{
+			CheckInterval: time.Millisecond,
+		})
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 200; j++ {
+					switch (i + j) % 4 {
+					case 0:
+						h.Close()
+					case 1:
+						h.Reset()
+					default:
+						h.Beat()
+						_ = h.Ctx().Err()
+						_ = h.Stats()
+					}
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		h.Close()
+		require.Equal(t, heartbeat.StateClosed, h.State())
+		require.ErrorIs(t, h.Ctx().Err(), context.Canceled)
+	})
+}
+
+func TestHeartbeat_WatchAlive(t *testing.T) {
+	t.Parallel()
+
+	t.Run("watched context cancelled", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), time.Second, nil)
+		defer h.Close()
+
+		errStop := errors.New("stop")
+		watched, cancel := context.WithCancelCause(context.Background())
+		h.WatchAlive(watched)
+
+		time.Sleep(50 * time.Millisecond)
+		require.NoError(t, h.Ctx().Err())
+
+		cancel(errStop)
+		require.Eventually(t, func() bool { return h.Ctx().Err() != nil }, time.Second, 5*time.Millisecond)
+		require.ErrorIs(t, context.Cause(h.Ctx()), errStop)
+		require.Equal(t, heartbeat.StateClosed, h.State())
+	})
+
+	t.Run("timeout still applies", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), 100*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+		})
+		defer h.Close()
+
+		watched, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		h.WatchAlive(watched)
+
+		<-h.Ctx().Done()
+		require.ErrorIs(t, context.Cause(h.Ctx()), heartbeat.ErrTimeout)
+	})
+}
+
+func TestHeartbeat_Close_fromHook(t *testing.T) {
+	t.Parallel()
+
+	m := heartbeat.NewManager(10 * time.Millisecond)
+	defer m.Close()
+
+	newHeartbeat := func(m *heartbeat.Manager, timeout time.Duration, opts *heartbeat.Options) *heartbeat.Heartbeat {
+		if m != nil {
+			return m.New(context.Background(), timeout, opts)
+		}
+		return heartbeat.New(context.Background(), timeout, opts)
+	}
+
+	for _, manager := range []*heartbeat.Manager{nil, m} {
+		tests := []struct {
+			name    string
+			timeout time.Duration
+			hook    func(opts *heartbeat.Options, closeFn func())
+		}{
+			{"check hook", time.Minute, func(opts *heartbeat.Options, closeFn func()) {
+				opts.CheckHook = func(_, _, _ time.Duration) { closeFn() }
+			}},
+			{"cancel hook", 20 * time.Millisecond, func(opts *heartbeat.Options, closeFn func()) {
+				opts.CancelHook = func(_, _, _ time.Duration) { closeFn() }
+			}},
+			{"guarded cancel hook", 20 * time.Millisecond, func(opts *heartbeat.Options, closeFn func()) {
+				// The slow check hook makes the guard expire the Heartbeat.
+				opts.CheckHook = func(_, _, _ time.Duration) { time.Sleep(50 * time.Millisecond) }
+				opts.CancelHook = func(_, _, _ time.Duration) { closeFn() }
+			}},
+			{"state hook", 20 * time.Millisecond, func(opts *heartbeat.Options, closeFn func()) {
+				opts.StateHook = func(_, to heartbeat.State) {
+					if to == heartbeat.StateExpired {
+						closeFn()
+					}
+				}
+			}},
+		}
+
+		for _, tt := range tests {
+			var hp atomic.Pointer[heartbeat.Heartbeat]
+			closed := make(chan struct{})
+			var once sync.Once
+			opts := &heartbeat.Options{CheckInterval: 10 * time.Millisecond}
+			tt.hook(opts, func() {
+				once.Do(func() {
+					for hp.Load() == nil {
+						time.Sleep(time.Millisecond)
+					}
+					hp.Load().Close()
+					close(closed)
+				})
+			})
+
+			h := newHeartbeat(manager, tt.timeout, opts)
+			hp.Store(h)
+			select {
+			case <-closed:
+			case <-time.After(time.Second):
+				t.Fatalf("%s, manager %v: Close from the hook deadlocked", tt.name, manager != nil)
+			}
+			require.Error(t, h.Ctx().Err())
+			h.Close()
+		}
+	}
+}
+
+// Not parallel, it counts the watching goroutines.
+func TestHeartbeat_CloseOn(t *testing.T) {
+	// watchers returns the number of the running watching goroutines.
+	watchers := func() int {
+		buf := make([]byte, 1<<20)
+		buf = buf[:runtime.Stack(buf, true)]
+		return strings.Count(string(buf), "heartbeat.(*Heartbeat).CloseOn.func")
+	}
+
+	t.Run("channel first", func(t *testing.T) {
+		h := heartbeat.New(context.Background(), time.Minute, nil)
+		defer h.Close()
+
+		done := make(chan struct{})
+		h.CloseOn(done)
+		require.Equal(t, 1, watchers())
+		require.NoError(t, h.Ctx().Err())
+
+		close(done)
+		require.Eventually(t, func() bool { return h.State() == heartbeat.StateClosed }, time.Second, 5*time.Millisecond)
+		require.ErrorIs(t, h.Ctx().Err(), context.Canceled)
+		require.Eventually(t, func() bool { return watchers() == 0 }, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("heartbeat first", func(t *testing.T) {
+		h := heartbeat.New(context.Background(), time.Minute, nil)
+
+		done := make(chan struct{})
+		defer close(done)
+		h.CloseOn(done)
+		require.Equal(t, 1, watchers())
+
+		h.Close()
+		require.Eventually(t, func() bool { return watchers() == 0 }, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("finalizer", func(t *testing.T) {
+		done := make(chan struct{})
+		defer close(done)
+		ctx := func() context.Context {
+			h := heartbeat.New(context.Background(), time.Hour, &heartbeat.Options{
+				SetFinalizer: true,
+			})
+			h.CloseOn(done)
+			return h.Ctx()
+		}()
+
+		for i := 0; i < 50 && ctx.Err() == nil; i++ {
+			runtime.GC()
+			time.Sleep(10 * time.Millisecond)
+		}
+		require.ErrorIs(t, context.Cause(ctx), context.Canceled)
+		require.Eventually(t, func() bool { return watchers() == 0 }, time.Second, 5*time.Millisecond)
+	})
+}
+
+func TestOptions_StarvationHook(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	h := heartbeat.New(context.Background(), time.Minute, &heartbeat.Options{
+		CheckInterval: 20 * time.Millisecond,
+		// Every check is a little late.
+		StarvationThreshold: time.Nanosecond,
+		StarvationHook: func(time.Duration) {
+			calls.Add(1)
+		},
+	})
+	defer h.Close()
+
+	time.Sleep(110 * time.Millisecond)
+	stats := h.Stats()
+	require.Greater(t, stats.MaxCheckLateness, time.Duration(0))
+	require.LessOrEqual(t, stats.MeanCheckLateness, stats.MaxCheckLateness)
+	require.GreaterOrEqual(t, calls.Load(), int32(3))
+}
+
+func TestHeartbeat_NextCheckIn(t *testing.T) {
+	t.Parallel()
+
+	h := heartbeat.New(context.Background(), time.Minute, &heartbeat.Options{
+		CheckInterval: 100 * time.Millisecond,
+	})
+	defer h.Close()
+
+	require.InDelta(t, 100*time.Millisecond, h.NextCheckIn(), float64(10*time.Millisecond))
+	time.Sleep(130 * time.Millisecond)
+	require.InDelta(t, 70*time.Millisecond, h.NextCheckIn(), float64(20*time.Millisecond))
+
+	_, ok := h.CheckNow()
+	require.True(t, ok)
+	require.InDelta(t, 100*time.Millisecond, h.NextCheckIn(), float64(10*time.Millisecond))
+
+	h.Close()
+	require.Zero(t, h.NextCheckIn())
+}
+
+func TestHeartbeat_CheckNow(t *testing.T) {
+	t.Parallel()
+
+	var checks atomic.Int64
+	h := heartbeat.New(context.Background(), 50*time.Millisecond, &heartbeat.Options{
+		CheckInterval: time.Hour,
+		CheckHook: func(_, _, _ time.Duration) {
+			checks.Add(1)
+		},
+	})
+	defer h.Close()
+
+	r, ok := h.CheckNow()
+	require.True(t, ok)
+	require.False(t, r.Expired)
+	require.Equal(t, 50*time.Millisecond, r.Timeout)
+	require.Equal(t, r.Timeout, r.Idle+r.Left)
+	require.Equal(t, int64(1), checks.Load())
+
+	time.Sleep(60 * time.Millisecond)
+	require.NoError(t, h.Ctx().Err(), "no periodic check yet")
+	r, ok = h.CheckNow()
+	require.False(t, ok)
+	require.True(t, r.Expired)
+	require.Equal(t, heartbeat.ReasonIdle, r.Reason)
+	require.ErrorIs(t, context.Cause(h.Ctx()), heartbeat.ErrTimeout)
+	require.Equal(t, int64(1), checks.Load())
+
+	r, ok = h.CheckNow()
+	require.False(t, ok)
+	require.Zero(t, r)
+}
+
+func TestHeartbeat_CheckNow_noAllocs(t *testing.T) {
+	// The checks guarding the hooks reuse the timer, they start no goroutine.
+	h := heartbeat.New(context.Background(), time.Minute, &heartbeat.Options{
+		CheckInterval: time.Hour,
+		CheckHook:     func(_, _, _ time.Duration) {},
+	})
+	defer h.Close()
+
+	h.CheckNow()
+	require.Zero(t, testing.AllocsPerRun(100, func() {
+		h.CheckNow()
+	}))
+}
+
+func TestHeartbeat_TickC(t *testing.T) {
+	t.Parallel()
+
+	t.Run("own goroutine", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), 100*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+		})
+		defer h.Close()
+
+		var results []heartbeat.CheckResult
+		for r := range h.TickC() {
+			results = append(results, r)
+		}
+		require.GreaterOrEqual(t, len(results), 5)
+		last := results[len(results)-1]
+		require.True(t, last.Expired)
+		require.Equal(t, heartbeat.ReasonIdle, last.Reason)
+		for _, r := range results[:len(results)-1] {
+			require.False(t, r.Expired)
+			require.Equal(t, r.Timeout, r.Idle+r.Left)
+		}
+
+		_, ok := <-h.TickC()
+		require.False(t, ok, "closed once done")
+
+		h.Reset()
+		c := h.TickC()
+		h.Close()
+		for range c {
+		}
+	})
+
+	t.Run("manager", func(t *testing.T) {
+		t.Parallel()
+
+		m := heartbeat.NewManager(10 * time.Millisecond)
+		defer m.Close()
+		h := m.New(context.Background(), time.Minute, nil)
+
+		c := h.TickC()
+		r := <-c
+		require.False(t, r.Expired)
+		h.Reset()
+		for range c {
+		}
+		h.Close()
+		for range h.TickC() {
+		}
+	})
+}
+
+func TestHeartbeat_Pause(t *testing.T) {
+	t.Parallel()
+
+	var checks atomic.Int64
+	h := heartbeat.New(context.Background(), 50*time.Millisecond, &heartbeat.Options{
+		CheckInterval: 10 * time.Millisecond,
+		CheckHook: func(_, _, _ time.Duration) {
+			checks.Add(1)
+		},
+	})
+	defer h.Close()
+
+	h.Pause()
+	start := checks.Load()
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, h.Ctx().Err())
+	require.LessOrEqual(t, checks.Load(), start+1, "no hooks while paused")
+
+	h.Resume()
+	require.Greater(t, h.Remaining(), 40*time.Millisecond)
+	<-h.Ctx().Done()
+	require.ErrorIs(t, context.Cause(h.Ctx()), heartbeat.ErrTimeout)
+}
+
+func TestHeartbeat_ForceExpire(t *testing.T) {
+	t.Parallel()
+
+	var hooks atomic.Int64
+	var hookIdle atomic.Int64
+	h := heartbeat.New(context.Background(), time.Minute, &heartbeat.Options{
+		DontCancel: true,
+		CancelHook: func(_, idle, _ time.Duration) {
+			hooks.Add(1)
+			hookIdle.Store(int64(idle))
+		},
+	})
+	defer h.Close()
+
+	time.Sleep(20 * time.Millisecond)
+	errKilled := errors.New("killed by admin")
+	require.NoError(t, h.ForceExpire(errKilled))
+
+	require.Equal(t, heartbeat.StateExpired, h.State())
+	require.Equal(t, heartbeat.ReasonForced, h.CancelReason())
+	cause := context.Cause(h.Ctx())
+	require.ErrorIs(t, cause, heartbeat.ErrTimeout)
+	require.ErrorIs(t, cause, errKilled)
+	require.EqualError(t, cause, "heartbeat: timeout: forced: killed by admin")
+	require.Equal(t, int64(1), hooks.Load())
+	require.GreaterOrEqual(t, time.Duration(hookIdle.Load()), 20*time.Millisecond)
+
+	require.ErrorIs(t, h.ForceExpire(nil), heartbeat.ErrNotRunning)
+	require.Equal(t, int64(1), hooks.Load())
+
+	closed := heartbeat.New(context.Background(), time.Minute, nil)
+	closed.Close()
+	require.ErrorIs(t, closed.ForceExpire(nil), heartbeat.ErrNotRunning)
+}
+
+func TestHeartbeat_StateHook(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var transitions []string
+	opts := &heartbeat.Options{
+		CheckInterval: 10 * time.Millisecond,
+		StateHook: func(from, to heartbeat.State) {
+			mu.Lock()
+			defer mu.Unlock()
+			transitions = append(transitions, from.String()+"->"+to.String())
+		},
+	}
+	recorded := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), transitions...)
+	}
+
+	parent, cancel := context.WithCancel(context.Background())
+	h := heartbeat.New(parent, 50*time.Millisecond, opts)
+	<-h.Ctx().Done()
+	h.Reset()
+	h.Close()
+	h.Close()
+	h.Reset()
+	cancel()
+	require.Eventually(t, func() bool { return h.State() == heartbeat.StateCancelled }, time.Second, time.Millisecond)
+	h.Close()
+
+	require.Equal(t, []string{
+		"running->expired",
+		"expired->running",
+		"running->closed",
+		"closed->running",
+		"running->cancelled",
+	}, recorded())
+}
+
+func TestHeartbeat_CancelReason(t *testing.T) {
+	t.Parallel()
+
+	// run beats h every 20ms if beat is set until its context is done, and waits for the expiry.
+	run := func(t *testing.T, h *heartbeat.Heartbeat, beat bool) {
+		t.Helper()
+
+		for h.Ctx().Err() == nil {
+			if beat {
+				h.Beat()
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+
+		require.Eventually(t, func() bool {
+			return h.State() == heartbeat.StateExpired
+		}, time.Second, 5*time.Millisecond)
+	}
+
+	t.Run("idle timeout is the earliest", func(t *testing.T) {
+		t.Parallel()
+
+		parent, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		var reported atomic.Bool
+		h := heartbeat.New(parent, 200*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+			MaxLifetime:   time.Second,
+			CancelHook: func(_, _, _ time.Duration) {
+				reported.Store(true)
+			},
+		})
+		defer h.Close()
+		run(t, h, false)
+
+		require.Equal(t, heartbeat.ReasonIdle, h.CancelReason())
+		var terr *heartbeat.TimeoutError
+		require.ErrorAs(t, context.Cause(h.Ctx()), &terr)
+		require.Equal(t, heartbeat.ReasonIdle, terr.Reason)
+		require.True(t, reported.Load())
+	})
+
+	t.Run("max lifetime is the earliest", func(t *testing.T) {
+		t.Parallel()
+
+		parent, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		var reported atomic.Bool
+		h := heartbeat.New(parent, 200*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+			MaxLifetime:   300 * time.Millisecond,
+			CancelHook: func(_, _, _ time.Duration) {
+				reported.Store(true)
+			},
+		})
+		defer h.Close()
+		start := time.Now()
+		run(t, h, true)
+
+		require.Equal(t, heartbeat.ReasonMaxLifetime, h.CancelReason())
+		var terr *heartbeat.TimeoutError
+		require.ErrorAs(t, context.Cause(h.Ctx()), &terr)
+		require.Equal(t, heartbeat.ReasonMaxLifetime, terr.Reason)
+		require.InDelta(t, 300*time.Millisecond, time.Since(start), float64(50*time.Millisecond))
+		require.True(t, reported.Load())
+	})
+
+	t.Run("parent deadline is the earliest", func(t *testing.T) {
+		t.Parallel()
+
+		parent, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+
+		var reported atomic.Bool
+		h := heartbeat.New(parent, 200*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+			MaxLifetime:   time.Second,
+			CancelHook: func(_, _, _ time.Duration) {
+				reported.Store(true)
+			},
+		})
+		defer h.Close()
+		run(t, h, true)
+
+		require.True(t, reported.Load())
+		require.Equal(t, heartbeat.ReasonParentDeadline, h.CancelReason())
+		require.ErrorIs(t, context.Cause(h.Ctx()), context.DeadlineExceeded)
+	})
+
+	t.Run("no first beat", func(t *testing.T) {
+		t.Parallel()
+
+		opts := &heartbeat.Options{
+			CheckInterval:          10 * time.Millisecond,
+			RequireFirstBeatWithin: 100 * time.Millisecond,
+		}
+		h := heartbeat.New(context.Background(), time.Second, opts)
+		defer h.Close()
+		start := time.Now()
+		run(t, h, false)
+
+		require.Equal(t, heartbeat.ReasonNoFirstBeat, h.CancelReason())
+		require.ErrorContains(t, context.Cause(h.Ctx()), "no first beat for ")
+		require.InDelta(t, 100*time.Millisecond, time.Since(start), float64(50*time.Millisecond))
+
+		// Once beaten, the full timeout applies.
+		beaten := heartbeat.New(context.Background(), 300*time.Millisecond, opts)
+		defer beaten.Close()
+		beaten.Beat()
+		time.Sleep(200 * time.Millisecond)
+		require.NoError(t, beaten.Ctx().Err())
+
+		// Reset requires the first beat again.
+		beaten.Reset()
+		require.False(t, beaten.UserBeaten())
+		run(t, beaten, false)
+		require.Equal(t, heartbeat.ReasonNoFirstBeat, beaten.CancelReason())
+	})
+
+	t.Run("escalation", func(t *testing.T) {
+		t.Parallel()
+
+		var soft atomic.Int32
+		opts := &heartbeat.Options{
+			CheckInterval:   10 * time.Millisecond,
+			EscalationDelay: 200 * time.Millisecond,
+			SoftTimeoutHook: func(timeout, idle, _ time.Duration) {
+				assert.GreaterOrEqual(t, idle, timeout)
+				soft.Add(1)
+			},
+		}
+		h := heartbeat.New(context.Background(), 100*time.Millisecond, opts)
+		defer h.Close()
+
+		time.Sleep(200 * time.Millisecond)
+		require.Equal(t, int32(1), soft.Load())
+		require.NoError(t, h.Ctx().Err())
+
+		// A beat during the escalation cancels it, the next stall escalates again.
+		h.Beat()
+		start := time.Now()
+		run(t, h, false)
+		require.Equal(t, int32(2), soft.Load())
+		require.Equal(t, heartbeat.ReasonIdle, h.CancelReason())
+		require.InDelta(t, 300*time.Millisecond, time.Since(start), float64(50*time.Millisecond))
+	})
+
+	t.Run("close", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), time.Second, nil)
+		h.Close()
+		require.Equal(t, heartbeat.ReasonNone, h.CancelReason())
+	})
+}
+
+func TestHeartbeat_parentCancelled(t *testing.T) {
+	t.Parallel()
+
+	var hookCount atomic.Int64
+	parent, cancel := context.WithCancel(context.Background())
+	h := heartbeat.New(parent, time.Second, &heartbeat.Options{
+		CheckInterval: 10 * time.Millisecond,
+		ParentCancelHook: func(timeout, idle, left time.Duration) {
+			hookCount.Add(1)
+			assert.Equal(t, time.Second, timeout)
+		},
+	})
+	defer h.Close()
+
+	cancel()
+	require.Eventually(t, func() bool {
+		return h.State() == heartbeat.StateCancelled
+	}, time.Second, 5*time.Millisecond)
+	require.Equal(t, "cancelled", h.Stats().State.String())
+	require.Equal(t, heartbeat.ReasonNone, h.CancelReason())
+	require.Equal(t, int64(1), hookCount.Load())
+
+	h.Close()
+	require.Equal(t, heartbeat.StateCancelled, h.State())
+	require.Equal(t, int64(1), hookCount.Load())
+}
+
+// TestHeartbeat_noLeaks checks that the checking goroutine exits on every termination path.
+// It is not parallel, so that only its own goroutines are counted.
+func TestHeartbeat_noLeaks(t *testing.T) {
+	// monitors returns the number of the running checking goroutines.
+	monitors := func() int {
+		buf := make([]byte, 1<<20)
+		buf = buf[:runtime.Stack(buf, true)]
+		return strings.Count(string(buf), "heartbeat.(*heartbeat).monitor.func")
+	}
+	// Let the Heartbeats closed by the previous tests finish.
+	require.Eventually(t, func() bool { return monitors() == 0 }, time.Second, 5*time.Millisecond)
+
+	opts := &heartbeat.Options{CheckInterval: 10 * time.Millisecond}
+
+	timeout := heartbeat.New(context.Background(), 50*time.Millisecond, opts)
+	defer timeout.Close()
+
+	closed := heartbeat.New(context.Background(), time.Minute, opts)
+
+	parent, cancel := context.WithCancel(context.Background())
+	cancelled := heartbeat.New(parent, time.Minute, opts)
+	defer cancelled.Close()
+
+	require.Equal(t, 3, monitors())
+
+	closed.Close()
+	require.Equal(t, 2, monitors())
+	cancel()
+	<-timeout.Ctx().Done()
+
+	require.Eventually(t, func() bool { return monitors() == 0 }, time.Second, 5*time.Millisecond)
+	require.Equal(t, heartbeat.StateExpired, timeout.State())
+	require.Equal(t, heartbeat.StateClosed, closed.State())
+	require.Equal(t, heartbeat.StateCancelled, cancelled.State())
+}
@@ -0,0 +1,142 @@
+// Package heartbeatredis shares the liveness of a Heartbeat across processes through a Redis key with a TTL.
+// It is built around a small Client interface, e.g. implemented by a thin adapter of a Redis client library,
+// so that it doesn't depend on one.
+package heartbeatredis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+// Client is the part of a Redis client used by the package.
+type Client interface {
+	// Set sets key to value expiring after ttl, i.e. SET key value PX ttl.
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	// Get returns the value of key, ok is false if the key doesn't exist.
+	Get(ctx context.Context, key string) (value string, ok bool, err error)
+}
+
+// Options defines optional parameters of Publish and Subscribe.
+type Options struct {
+	// Heartbeat is the options of the Heartbeat created by Subscribe.
+	Heartbeat *heartbeat.Options
+	// Interval is the interval of the refreshes by Publish and of the polls by Subscribe,
+	// a quarter of the timeout by default.
+	Interval time.Duration
+	// ErrorHook is called on every error of the client, e.g. to log the network errors.
+	ErrorHook func(err error)
+}
+
+// Publish refreshes key with the time of the last beat of h, expiring after the timeout of h, whenever h has been
+// beaten since the previous refresh. The refreshes are limited to one per Options.Interval.
+// Once h stops beating, the key expires with its timeout.
+//
+// Publishing stops when stop is called, when ctx or the context of h is done.
+func Publish(ctx context.Context, client Client, key string, h *heartbeat.Heartbeat, opts *Options) (stop func()) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = h.Stats().Timeout / 4
+	}
+
+	stopc := make(chan struct{})
+	exited := make(chan struct{})
+
+	go func() {
+		defer close(exited)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var published time.Time
+		for {
+			stats := h.Stats()
+			if !stats.LastBeat.Equal(published) && stats.Remaining > 0 {
+				value := strconv.FormatInt(stats.LastBeat.UnixNano(), 10)
+				if err := client.Set(ctx, key, value, stats.Timeout); err != nil {
+					report(opts, fmt.Errorf("heartbeatredis: publish %s: %w", key, err))
+				} else {
+					published = stats.LastBeat
+				}
+			}
+
+			select {
+			case <-stopc:
+				return
+			case <-ctx.Done():
+				return
+			case <-h.Ctx().Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(stopc)
+		})
+		<-exited
+	}
+}
+
+// Subscribe creates a Heartbeat with the given timeout beaten whenever key is observed refreshed, e.g. by Publish
+// from another process. key is polled every Options.Interval. A missing key is not a beat, so the Heartbeat
+// expires once the key does. The value at the start is not a beat either.
+//
+// Polling stops when the Heartbeat context is done, Close() must be called as usual.
+func Subscribe(ctx context.Context, client Client, key string, timeout time.Duration, opts *Options) *heartbeat.Heartbeat {
+	if opts == nil {
+		opts = &Options{}
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = timeout / 4
+	}
+
+	h := heartbeat.New(ctx, timeout, opts.Heartbeat)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		prev, _, err := client.Get(h.Ctx(), key)
+		if err != nil {
+			report(opts, fmt.Errorf("heartbeatredis: subscribe %s: %w", key, err))
+		}
+
+		for {
+			select {
+			case <-h.Ctx().Done():
+				return
+			case <-ticker.C:
+				value, ok, err := client.Get(h.Ctx(), key)
+				if err != nil {
+					if h.Ctx().Err() == nil {
+						report(opts, fmt.Errorf("heartbeatredis: subscribe %s: %w", key, err))
+					}
+					continue
+				}
+				if ok && value != prev {
+					h.Beat()
+				}
+				prev = value
+			}
+		}
+	}()
+
+	return h
+}
+
+func report(opts *Options, err error) {
+	if opts.ErrorHook != nil {
+		opts.ErrorHook(err)
+	}
+}
@@ -0,0 +1,115 @@
+package heartbeatredis_test
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+	"ytils.dev/heartbeat/heartbeatredis"
+)
+
+// client is a fake Redis holding the keys in memory.
+type client struct {
+	mu     sync.Mutex
+	values map[string]string
+	expiry map[string]time.Time
+	sets   atomic.Int64
+	down   atomic.Bool
+}
+
+var errDown = errors.New("connection refused")
+
+func newClient() *client {
+	return &client{values: make(map[string]string), expiry: make(map[string]time.Time)}
+}
+
+func (c *client) Set(_ context.Context, key, value string, ttl time.Duration) error {
+	if c.down.Load() {
+		return errDown
+	}
+	c.sets.Add(1)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] = value
+	c.expiry[key] = time.Now().Add(ttl)
+	return nil
+}
+
+func (c *client) Get(_ context.Context, key string) (string, bool, error) {
+	if c.down.Load() {
+		return "", false, errDown
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if time.Now().After(c.expiry[key]) {
+		delete(c.values, key)
+	}
+	value, ok := c.values[key]
+	return value, ok, nil
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	t.Parallel()
+
+	c := newClient()
+	opts := &heartbeatredis.Options{
+		Heartbeat: &heartbeat.Options{CheckInterval: 10 * time.Millisecond},
+		Interval:  10 * time.Millisecond,
+	}
+
+	local := heartbeat.New(context.Background(), 100*time.Millisecond, nil)
+	defer local.Close()
+	stop := heartbeatredis.Publish(context.Background(), c, "job", local, opts)
+	defer stop()
+
+	remote := heartbeatredis.Subscribe(context.Background(), c, "job", 100*time.Millisecond, opts)
+	defer remote.Close()
+
+	for i := 0; i < 20; i++ {
+		local.Beat()
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.NoError(t, remote.Ctx().Err())
+	require.Greater(t, remote.Stats().Beats, uint64(5))
+	// Rate-limited to one per interval.
+	require.LessOrEqual(t, c.sets.Load(), int64(22))
+
+	// The local operation stalls, the key expires.
+	<-remote.Ctx().Done()
+	require.ErrorIs(t, context.Cause(remote.Ctx()), heartbeat.ErrTimeout)
+}
+
+func TestErrorHook(t *testing.T) {
+	t.Parallel()
+
+	c := newClient()
+	c.down.Store(true)
+
+	var errs atomic.Int64
+	opts := &heartbeatredis.Options{
+		Heartbeat: &heartbeat.Options{CheckInterval: 10 * time.Millisecond},
+		Interval:  10 * time.Millisecond,
+		ErrorHook: func(err error) {
+			if errors.Is(err, errDown) {
+				errs.Add(1)
+			}
+		},
+	}
+
+	local := heartbeat.New(context.Background(), time.Minute, nil)
+	defer local.Close()
+	stop := heartbeatredis.Publish(context.Background(), c, "job", local, opts)
+
+	remote := heartbeatredis.Subscribe(context.Background(), c, "job", 100*time.Millisecond, opts)
+	defer remote.Close()
+
+	<-remote.Ctx().Done()
+	stop()
+	require.Greater(t, errs.Load(), int64(5))
+}
@@ -0,0 +1,90 @@
+// Package heartbeatsd forwards the liveness of a Heartbeat to the systemd watchdog (sd_notify protocol).
+package heartbeatsd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+const (
+	watchdogMsg = "WATCHDOG=1"
+	stoppingMsg = "STOPPING=1"
+)
+
+// Notify keeps the systemd watchdog happy as long as the Heartbeat is alive.
+//
+// The socket and the watchdog interval are read from the NOTIFY_SOCKET and WATCHDOG_USEC environment variables.
+// "WATCHDOG=1" is sent at half of the watchdog interval while the Heartbeat has been beaten within its timeout,
+// so systemd restarts the service once the Heartbeat expires. "STOPPING=1" is sent when the Heartbeat is closed
+// or its parent context is cancelled.
+//
+// Notify is a no-op if the environment variables are absent or WATCHDOG_PID is set to another process.
+// The notifications are best-effort: send errors are ignored.
+func Notify(h *heartbeat.Heartbeat) error {
+	socket, interval, err := watchdogFromEnv()
+	if err != nil || socket == "" {
+		return err
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return fmt.Errorf("heartbeatsd: dial notify socket: %w", err)
+	}
+
+	go func() {
+		defer conn.Close()
+
+		ticker := time.NewTicker(interval / 2)
+		defer ticker.Stop()
+
+		_, _ = conn.Write([]byte(watchdogMsg))
+
+		for {
+			select {
+			case <-h.Ctx().Done():
+				if !errors.Is(context.Cause(h.Ctx()), heartbeat.ErrTimeout) {
+					_, _ = conn.Write([]byte(stoppingMsg))
+				}
+				return
+			case <-ticker.C:
+				if h.Remaining() > 0 {
+					_, _ = conn.Write([]byte(watchdogMsg))
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// watchdogFromEnv returns the notify socket address and the watchdog interval.
+// The socket is empty if the watchdog is not enabled for the current process.
+func watchdogFromEnv() (string, time.Duration, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	usec := os.Getenv("WATCHDOG_USEC")
+	if socket == "" || usec == "" {
+		return "", 0, nil
+	}
+
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return "", 0, nil
+	}
+
+	n, err := strconv.ParseInt(usec, 10, 64)
+	if err != nil || n <= 0 {
+		return "", 0, fmt.Errorf("heartbeatsd: invalid WATCHDOG_USEC %q", usec)
+	}
+
+	// Abstract namespace sockets are denoted by a leading '@'.
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+
+	return socket, time.Duration(n) * time.Microsecond, nil
+}
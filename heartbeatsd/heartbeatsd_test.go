@@ -0,0 +1,100 @@
+//go:build unix
+
+package heartbeatsd_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"net"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+	"ytils.dev/heartbeat/heartbeatsd"
+)
+
+func listen(t *testing.T) *net.UnixConn {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	t.Setenv("NOTIFY_SOCKET", path)
+	t.Setenv("WATCHDOG_USEC", strconv.Itoa(int((100 * time.Millisecond).Microseconds())))
+
+	return conn
+}
+
+func read(t *testing.T, conn *net.UnixConn, wait time.Duration) []string {
+	t.Helper()
+
+	var msgs []string
+	buf := make([]byte, 64)
+	deadline := time.Now().Add(wait)
+	for {
+		require.NoError(t, conn.SetReadDeadline(deadline))
+		n, err := conn.Read(buf)
+		if err != nil {
+			return msgs
+		}
+		msgs = append(msgs, string(buf[:n]))
+	}
+}
+
+func TestNotify(t *testing.T) {
+	t.Run("no-op without env", func(t *testing.T) {
+		t.Setenv("NOTIFY_SOCKET", "")
+		t.Setenv("WATCHDOG_USEC", "")
+
+		h := heartbeat.New(context.Background(), time.Second, nil)
+		defer h.Close()
+
+		require.NoError(t, heartbeatsd.Notify(h))
+	})
+
+	t.Run("invalid interval", func(t *testing.T) {
+		listen(t)
+		t.Setenv("WATCHDOG_USEC", "abc")
+
+		h := heartbeat.New(context.Background(), time.Second, nil)
+		defer h.Close()
+
+		require.Error(t, heartbeatsd.Notify(h))
+	})
+
+	t.Run("watchdog while alive, stopping on close", func(t *testing.T) {
+		conn := listen(t)
+
+		h := heartbeat.New(context.Background(), time.Second, nil)
+		require.NoError(t, heartbeatsd.Notify(h))
+
+		msgs := read(t, conn, 300*time.Millisecond)
+		require.GreaterOrEqual(t, len(msgs), 5)
+		for _, msg := range msgs {
+			require.Equal(t, "WATCHDOG=1", msg)
+		}
+
+		h.Close()
+		msgs = read(t, conn, 100*time.Millisecond)
+		require.NotEmpty(t, msgs)
+		require.Equal(t, "STOPPING=1", msgs[len(msgs)-1])
+	})
+
+	t.Run("no watchdog after expiry", func(t *testing.T) {
+		conn := listen(t)
+
+		h := heartbeat.New(context.Background(), 200*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 20 * time.Millisecond,
+		})
+		defer h.Close()
+		require.NoError(t, heartbeatsd.Notify(h))
+
+		<-h.Ctx().Done()
+		read(t, conn, 50*time.Millisecond) // drain the notifications sent before expiry
+
+		require.Empty(t, read(t, conn, 200*time.Millisecond))
+	})
+}
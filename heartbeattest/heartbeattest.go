@@ -0,0 +1,56 @@
+// Package heartbeattest provides a fake heartbeat.Heartbeater for tests.
+package heartbeattest
+
+import (
+	"context"
+	"sync/atomic"
+	"ytils.dev/heartbeat"
+)
+
+// Heartbeater is a fake heartbeat.Heartbeater recording the calls. It never expires by itself:
+// its context is cancelled only by Close(), Expire() or the parent context.
+type Heartbeater struct {
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+
+	beats  atomic.Uint64
+	closed atomic.Bool
+}
+
+// New returns a Heartbeater with the context derived from ctx.
+func New(ctx context.Context) *Heartbeater {
+	hctx, cancel := context.WithCancelCause(ctx)
+	return &Heartbeater{ctx: hctx, cancel: cancel}
+}
+
+// Beat records the beat.
+func (h *Heartbeater) Beat() {
+	h.beats.Add(1)
+}
+
+// Ctx returns the context controlled by the Heartbeater.
+func (h *Heartbeater) Ctx() context.Context {
+	return h.ctx
+}
+
+// Close records the call and cancels the context.
+func (h *Heartbeater) Close() {
+	h.closed.Store(true)
+	h.cancel(nil)
+}
+
+// Expire cancels the context the same way as an expired heartbeat.Heartbeat,
+// with a *heartbeat.TimeoutError cause matching heartbeat.ErrTimeout.
+func (h *Heartbeater) Expire() {
+	h.cancel(&heartbeat.TimeoutError{Reason: heartbeat.ReasonIdle})
+}
+
+// Beats returns the number of the Beat() calls.
+func (h *Heartbeater) Beats() uint64 {
+	return h.beats.Load()
+}
+
+// Closed reports whether Close() was called.
+func (h *Heartbeater) Closed() bool {
+	return h.closed.Load()
+}
@@ -0,0 +1,52 @@
+package heartbeattest_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+	"ytils.dev/heartbeat/heartbeattest"
+)
+
+func TestHeartbeater(t *testing.T) {
+	t.Parallel()
+
+	// work stands for the code under test accepting the interface.
+	work := func(h heartbeat.Heartbeater, n int) error {
+		defer h.Close()
+		for i := 0; i < n; i++ {
+			if err := h.Ctx().Err(); err != nil {
+				return context.Cause(h.Ctx())
+			}
+			h.Beat()
+		}
+		return nil
+	}
+
+	t.Run("beats and close", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeattest.New(context.Background())
+		require.NoError(t, work(h, 3))
+		require.Equal(t, uint64(3), h.Beats())
+		require.True(t, h.Closed())
+		require.ErrorIs(t, h.Ctx().Err(), context.Canceled)
+	})
+
+	t.Run("expire", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeattest.New(context.Background())
+		h.Expire()
+		require.ErrorIs(t, work(h, 3), heartbeat.ErrTimeout)
+		require.Zero(t, h.Beats())
+	})
+
+	t.Run("implemented by Heartbeat", func(t *testing.T) {
+		t.Parallel()
+
+		var h heartbeat.Heartbeater = heartbeat.New(context.Background(), time.Second, nil)
+		h.Close()
+	})
+}
@@ -0,0 +1,94 @@
+// Package heartbeatws keeps a Heartbeat per WebSocket connection alive on the pongs and the messages received.
+// It is built around small interfaces, e.g. satisfied by *websocket.Conn of github.com/gorilla/websocket,
+// so that it doesn't depend on a WebSocket library.
+package heartbeatws
+
+import (
+	"sync"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+// PingMessage is the opcode of the ping control frame (RFC 6455).
+const PingMessage = 9
+
+// PingPonger is the part of a WebSocket connection used by Bind.
+type PingPonger interface {
+	// SetPongHandler sets the handler of the pongs received.
+	SetPongHandler(h func(appData string) error)
+	// WriteControl writes a control message with the given deadline.
+	WriteControl(messageType int, data []byte, deadline time.Time) error
+}
+
+// Options defines optional parameters of Bind.
+type Options struct {
+	// PingInterval is the interval of the pings sent while the Heartbeat is alive, typically a fraction
+	// of its timeout. No pings are sent if it is zero, e.g. when the peer pings by itself.
+	PingInterval time.Duration
+	// Closer is called once the Heartbeat context is done, e.g. the Close method of the connection,
+	// so that the blocked read loop returns.
+	Closer func() error
+}
+
+// Bind installs the pong handler of conn beating h and sends the pings, see Options.
+// Any other message received should beat h too, see BeatOnRead.
+// The returned function stops the pings and the closer, it doesn't close the connection.
+func Bind(h heartbeat.Beater, conn PingPonger, opts *Options) (stop func()) {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	conn.SetPongHandler(func(string) error {
+		h.Beat()
+		return nil
+	})
+
+	stopc := make(chan struct{})
+	exited := make(chan struct{})
+
+	go func() {
+		defer close(exited)
+
+		var pings <-chan time.Time
+		if opts.PingInterval > 0 {
+			ticker := time.NewTicker(opts.PingInterval)
+			defer ticker.Stop()
+			pings = ticker.C
+		}
+
+		for {
+			select {
+			case <-stopc:
+				return
+			case <-h.Ctx().Done():
+				if opts.Closer != nil {
+					_ = opts.Closer()
+				}
+				return
+			case now := <-pings:
+				// A failed ping shows up as a missing pong.
+				_ = conn.WriteControl(PingMessage, nil, now.Add(opts.PingInterval))
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(stopc)
+		})
+		<-exited
+	}
+}
+
+// BeatOnRead wraps the read function of a connection, e.g. the ReadMessage method of the connection,
+// so that every message received beats h.
+func BeatOnRead(h heartbeat.Beater, read func() (int, []byte, error)) func() (int, []byte, error) {
+	return func() (int, []byte, error) {
+		messageType, data, err := read()
+		if err == nil {
+			h.Beat()
+		}
+		return messageType, data, err
+	}
+}
@@ -0,0 +1,135 @@
+package heartbeatws_test
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+	"ytils.dev/heartbeat/heartbeatws"
+)
+
+// conn is a fake connection whose peer answers every ping with a pong unless it is unresponsive.
+type conn struct {
+	mu           sync.Mutex
+	pongHandler  func(string) error
+	pings        atomic.Int64
+	unresponsive atomic.Bool
+	closed       chan struct{}
+	closeOnce    sync.Once
+}
+
+func newConn() *conn {
+	return &conn{closed: make(chan struct{})}
+}
+
+func (c *conn) SetPongHandler(h func(appData string) error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pongHandler = h
+}
+
+func (c *conn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	if messageType != heartbeatws.PingMessage {
+		return errors.New("unexpected message type")
+	}
+	c.pings.Add(1)
+	if c.unresponsive.Load() {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pongHandler(string(data))
+}
+
+func (c *conn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+	})
+	return nil
+}
+
+// ReadMessage blocks until the connection is closed.
+func (c *conn) ReadMessage() (int, []byte, error) {
+	<-c.closed
+	return 0, nil, errors.New("closed")
+}
+
+func TestBind(t *testing.T) {
+	t.Parallel()
+
+	opts := &heartbeat.Options{CheckInterval: 10 * time.Millisecond}
+
+	t.Run("pongs beat, expiry closes", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), 100*time.Millisecond, opts)
+		defer h.Close()
+
+		c := newConn()
+		stop := heartbeatws.Bind(h, c, &heartbeatws.Options{
+			PingInterval: 30 * time.Millisecond,
+			Closer:       c.Close,
+		})
+		defer stop()
+
+		time.Sleep(300 * time.Millisecond)
+		require.NoError(t, h.Ctx().Err())
+		require.GreaterOrEqual(t, c.pings.Load(), int64(5))
+
+		c.unresponsive.Store(true)
+		start := time.Now()
+		_, _, err := c.ReadMessage()
+		require.Error(t, err)
+		require.Less(t, time.Since(start), 300*time.Millisecond)
+		require.ErrorIs(t, context.Cause(h.Ctx()), heartbeat.ErrTimeout)
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), 50*time.Millisecond, opts)
+		defer h.Close()
+
+		c := newConn()
+		stop := heartbeatws.Bind(h, c, &heartbeatws.Options{Closer: c.Close})
+		stop()
+		stop()
+
+		<-h.Ctx().Done()
+		time.Sleep(20 * time.Millisecond)
+		select {
+		case <-c.closed:
+			t.Fatal("closed after stop")
+		default:
+		}
+		require.Zero(t, c.pings.Load())
+	})
+}
+
+func TestBeatOnRead(t *testing.T) {
+	t.Parallel()
+
+	h := heartbeat.New(context.Background(), time.Second, nil)
+	defer h.Close()
+
+	messages := 0
+	read := heartbeatws.BeatOnRead(h, func() (int, []byte, error) {
+		messages++
+		if messages > 2 {
+			return 0, nil, errors.New("closed")
+		}
+		return 1, []byte("hello"), nil
+	})
+
+	for {
+		if _, _, err := read(); err != nil {
+			break
+		}
+	}
+	require.Equal(t, uint64(2), h.Stats().Beats)
+}
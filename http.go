@@ -0,0 +1,63 @@
+package heartbeat
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// BeatHandler returns an HTTP handler for the external systems that can only make HTTP calls.
+//
+// POST requests beat the Heartbeat, GET requests don't. Both are answered with the Stats snapshot as JSON.
+// If token is not empty, requests must carry it either as "Authorization: Bearer <token>" header
+// or as "token" query parameter, otherwise they are answered with 401 Unauthorized.
+// Once the Heartbeat context is done, requests beat nothing and are answered with 409 Conflict
+// and the cancellation cause as {"error": "..."}.
+func (h *Heartbeat) BeatHandler(token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		if token != "" && !validToken(r, token) {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		if h.Ctx().Err() != nil {
+			writeJSON(w, http.StatusConflict, struct {
+				Error string `json:"error"`
+			}{context.Cause(h.Ctx()).Error()})
+			return
+		}
+
+		if r.Method == http.MethodPost {
+			h.Beat()
+		}
+
+		writeJSON(w, http.StatusOK, h.Stats())
+	})
+}
+
+func validToken(r *http.Request, token string) bool {
+	got := r.URL.Query().Get("token")
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		scheme, credentials, _ := strings.Cut(auth, " ")
+		if !strings.EqualFold(scheme, "Bearer") {
+			return false
+		}
+		got = credentials
+	}
+
+	return subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
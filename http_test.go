@@ -0,0 +1,95 @@
+package heartbeat_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+func TestHeartbeat_BeatHandler(t *testing.T) {
+	t.Parallel()
+
+	newHeartbeat := func() *heartbeat.Heartbeat {
+		h := heartbeat.New(context.Background(), time.Second, &heartbeat.Options{
+			CheckInterval: 50 * time.Millisecond,
+		})
+		t.Cleanup(h.Close)
+		return h
+	}
+
+	serve := func(h http.Handler, method, target string, header http.Header) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, nil)
+		for k, v := range header {
+			r.Header[k] = v
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	bearer := http.Header{"Authorization": {"Bearer secret"}}
+
+	t.Run("methods", func(t *testing.T) {
+		t.Parallel()
+
+		h := newHeartbeat()
+		handler := h.BeatHandler("")
+
+		time.Sleep(300 * time.Millisecond)
+
+		w := serve(handler, http.MethodGet, "/", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		var stats map[string]any
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &stats))
+		assert.Contains(t, stats, "remaining")
+		assert.Less(t, h.Remaining(), 800*time.Millisecond)
+
+		w = serve(handler, http.MethodPost, "/", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Greater(t, h.Remaining(), 800*time.Millisecond)
+
+		w = serve(handler, http.MethodDelete, "/", nil)
+		require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+		assert.Equal(t, "GET, POST", w.Header().Get("Allow"))
+	})
+
+	t.Run("token", func(t *testing.T) {
+		t.Parallel()
+
+		handler := newHeartbeat().BeatHandler("secret")
+
+		assert.Equal(t, http.StatusOK, serve(handler, http.MethodPost, "/", bearer).Code)
+		assert.Equal(t, http.StatusOK, serve(handler, http.MethodPost, "/?token=secret", nil).Code)
+		assert.Equal(t, http.StatusUnauthorized, serve(handler, http.MethodPost, "/", nil).Code)
+		assert.Equal(t, http.StatusUnauthorized, serve(handler, http.MethodPost, "/?token=wrong", nil).Code)
+		assert.Equal(t, http.StatusUnauthorized,
+			serve(handler, http.MethodPost, "/", http.Header{"Authorization": {"Basic secret"}}).Code)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), 100*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+		})
+		defer h.Close()
+		handler := h.BeatHandler("secret")
+
+		<-h.Ctx().Done()
+
+		w := serve(handler, http.MethodPost, "/", bearer)
+		require.Equal(t, http.StatusConflict, w.Code)
+		var body struct {
+			Error string `json:"error"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Contains(t, body.Error, "heartbeat: timeout")
+		assert.LessOrEqual(t, h.Remaining(), time.Duration(0))
+	})
+}
@@ -0,0 +1,46 @@
+package heartbeat
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// latencyHistogram counts durations in power-of-two buckets: the bucket i holds [2^i, 2^(i+1)) nanoseconds.
+type latencyHistogram struct {
+	buckets [63]atomic.Uint64
+}
+
+func (l *latencyHistogram) observe(d time.Duration) {
+	if d < 1 {
+		d = 1
+	}
+	l.buckets[bits.Len64(uint64(d))-1].Add(1)
+}
+
+// percentile returns the upper bound of the bucket holding the p-th percentile, zero if nothing was observed.
+func (l *latencyHistogram) percentile(p float64) time.Duration {
+	var counts [len(l.buckets)]uint64
+	var total uint64
+	for i := range l.buckets {
+		counts[i] = l.buckets[i].Load()
+		total += counts[i]
+	}
+	if total == 0 {
+		return 0
+	}
+
+	rank := uint64(math.Ceil(p * float64(total)))
+	var cum uint64
+	for i, c := range counts {
+		cum += c
+		if cum >= rank {
+			if i == len(counts)-1 {
+				return math.MaxInt64
+			}
+			return time.Duration(1) << (i + 1)
+		}
+	}
+	return math.MaxInt64
+}
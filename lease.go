@@ -0,0 +1,149 @@
+package heartbeat
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileLease is a liveness channel between the processes on one host through a small file at Path:
+// the beating process keeps the file fresh with Writer and the supervising process beats its Heartbeat
+// with Watch whenever the file content changes. A stale file left by a crashed writer doesn't change,
+// so the watching Heartbeat expires.
+type FileLease struct {
+	Path string
+}
+
+// Writer rewrites the lease file with the current time and a random nonce right away and then every interval
+// while ctx is alive. The file is replaced atomically by a rename, so the watcher never reads a torn write.
+// stop ends rewriting and waits for the writing goroutine to exit, the file is left in place.
+func (l FileLease) Writer(ctx context.Context, interval time.Duration) (stop func(), err error) {
+	if interval <= 0 {
+		panic("positive interval is required")
+	}
+
+	if err := l.write(); err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	exited := make(chan struct{})
+
+	go func() {
+		defer close(exited)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				// A failed write is retried on the next tick, meanwhile the lease goes stale.
+				_ = l.write()
+			}
+		}
+	}()
+
+	var once sync.Once
+	stop = func() {
+		once.Do(func() { close(done) })
+		<-exited
+	}
+
+	return stop, nil
+}
+
+func (l FileLease) write() error {
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("heartbeat: file lease: %w", err)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(l.Path), filepath.Base(l.Path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("heartbeat: file lease: %w", err)
+	}
+	// Readable by the watchers like the files created by TouchFile, CreateTemp makes it private.
+	err = f.Chmod(0o644)
+	if err == nil {
+		_, err = fmt.Fprintf(f, "%d %s\n", time.Now().UnixNano(), hex.EncodeToString(nonce))
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), l.Path)
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return fmt.Errorf("heartbeat: file lease: %w", err)
+	}
+
+	return nil
+}
+
+// Watch reads the lease file every poll and beats h whenever its content changed since the last poll.
+// The content at the start is not a beat, nor is a missing file.
+//
+// Watching stops when stop is called or when the context of h is done.
+func (l FileLease) Watch(h Beater, poll time.Duration) (stop func(), err error) {
+	if poll <= 0 {
+		panic("positive poll interval is required")
+	}
+
+	prev, err := os.ReadFile(l.Path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("heartbeat: file lease: %w", err)
+	}
+
+	done := make(chan struct{})
+	exited := make(chan struct{})
+
+	go func() {
+		defer close(exited)
+
+		ticker := time.NewTicker(poll)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-h.Ctx().Done():
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				cur, err := os.ReadFile(l.Path)
+				if err != nil {
+					// Missing or inaccessible, the next appearance will be a change.
+					prev = nil
+					continue
+				}
+
+				if !bytes.Equal(prev, cur) {
+					h.Beat()
+				}
+				prev = cur
+			}
+		}
+	}()
+
+	var once sync.Once
+	stop = func() {
+		once.Do(func() { close(done) })
+		<-exited
+	}
+
+	return stop, nil
+}
@@ -0,0 +1,76 @@
+package heartbeat_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+func TestFileLease(t *testing.T) {
+	t.Parallel()
+
+	opts := &heartbeat.Options{CheckInterval: 10 * time.Millisecond}
+
+	t.Run("alive while written", func(t *testing.T) {
+		t.Parallel()
+
+		lease := heartbeat.FileLease{Path: filepath.Join(t.TempDir(), "lease")}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		stopWriter, err := lease.Writer(ctx, 20*time.Millisecond)
+		require.NoError(t, err)
+		defer stopWriter()
+
+		h := heartbeat.New(context.Background(), 100*time.Millisecond, opts)
+		defer h.Close()
+		stopWatch, err := lease.Watch(h, 10*time.Millisecond)
+		require.NoError(t, err)
+		defer stopWatch()
+
+		time.Sleep(300 * time.Millisecond)
+		require.NoError(t, h.Ctx().Err())
+		require.Greater(t, h.Stats().Beats, uint64(5))
+
+		// The writer's context is gone, the lease goes stale.
+		cancel()
+		<-h.Ctx().Done()
+		require.ErrorIs(t, context.Cause(h.Ctx()), heartbeat.ErrTimeout)
+
+		// No temporary files are left behind.
+		entries, err := os.ReadDir(filepath.Dir(lease.Path))
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+	})
+
+	t.Run("stale file expires", func(t *testing.T) {
+		t.Parallel()
+
+		lease := heartbeat.FileLease{Path: filepath.Join(t.TempDir(), "lease")}
+		stopWriter, err := lease.Writer(context.Background(), time.Hour)
+		require.NoError(t, err)
+		stopWriter()
+
+		h := heartbeat.New(context.Background(), 100*time.Millisecond, opts)
+		defer h.Close()
+		stopWatch, err := lease.Watch(h, 10*time.Millisecond)
+		require.NoError(t, err)
+		defer stopWatch()
+
+		<-h.Ctx().Done()
+		require.ErrorIs(t, context.Cause(h.Ctx()), heartbeat.ErrTimeout)
+		require.Zero(t, h.Stats().Beats)
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		t.Parallel()
+
+		lease := heartbeat.FileLease{Path: filepath.Join(t.TempDir(), "missing", "lease")}
+		_, err := lease.Writer(context.Background(), time.Second)
+		require.ErrorContains(t, err, "heartbeat: file lease: ")
+	})
+}
@@ -0,0 +1,345 @@
+package heartbeat
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Manager checks many Heartbeats from a single goroutine instead of a goroutine per Heartbeat.
+// The Heartbeats are kept in a min-heap keyed by their next check time, the goroutine sleeps until the earliest one.
+// It is useful when there are thousands of concurrent Heartbeats.
+type Manager struct {
+	interval time.Duration
+
+	mu      sync.Mutex
+	members map[*heartbeat]*member
+	queue   queue
+	paused  bool
+
+	wake    chan struct{}
+	stop    chan struct{}
+	exited  chan struct{}
+	wakeups atomic.Uint64
+
+	timeouts     uint64
+	overshootSum time.Duration
+	overshootMax time.Duration
+}
+
+// TimeoutStats describes the idle timeouts of the Heartbeats checked by a Manager, see Manager.TimeoutStats.
+type TimeoutStats struct {
+	// Count is the number of the idle timeouts.
+	Count uint64
+	// MeanOvershoot is the mean time the Heartbeats stayed idle past their timeout before the expiry.
+	// A mean close to the check interval tells the interval is too coarse for the timeouts.
+	MeanOvershoot time.Duration
+	// MaxOvershoot is the longest time a Heartbeat stayed idle past its timeout before the expiry.
+	MaxOvershoot time.Duration
+}
+
+// member is a Heartbeat checked by a Manager.
+type member struct {
+	h     *heartbeat
+	next  time.Time
+	index int
+}
+
+// queue is the min-heap of the members by the next check time.
+type queue []*member
+
+func (q queue) Len() int           { return len(q) }
+func (q queue) Less(i, j int) bool { return q[i].next.Before(q[j].next) }
+
+func (q queue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *queue) Push(x any) {
+	mb := x.(*member)
+	mb.index = len(*q)
+	*q = append(*q, mb)
+}
+
+func (q *queue) Pop() any {
+	old := *q
+	n := len(old) - 1
+	mb := old[n]
+	old[n] = nil
+	mb.index = -1
+	*q = old[:n]
+	return mb
+}
+
+// NewManager creates a Manager and starts checking its Heartbeats.
+// interval is the check interval of the Heartbeats without Options.CheckInterval.
+// A Heartbeat due within a tenth of its check interval is checked along with the earlier ones to save wakeups.
+// Close must be called to stop checking.
+func NewManager(interval time.Duration) *Manager {
+	if interval <= 0 {
+		panic("positive interval is required")
+	}
+
+	m := &Manager{
+		interval: interval,
+		members:  make(map[*heartbeat]*member),
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		exited:   make(chan struct{}),
+	}
+
+	go m.run()
+
+	return m
+}
+
+// New creates a Heartbeat checked by the Manager, see heartbeat.New.
+// The Heartbeat behaves the same, except Options.CheckInterval defaults to the interval of the Manager.
+func (m *Manager) New(ctx context.Context, timeout time.Duration, config *Options) *Heartbeat {
+	h := newHeartbeat(ctx, timeout, config)
+	if config == nil || config.CheckInterval <= 0 {
+		h.checkInterval = m.interval
+	}
+	h.lazy = false
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	select {
+	case <-m.stop:
+		panic("manager is closed")
+	default:
+	}
+
+	hb := h.heartbeat
+	hb.paused.Store(m.paused)
+	m.add(hb)
+	hb.wake = func() {
+		m.reschedule(hb)
+	}
+	hb.restart = func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		select {
+		case <-m.stop:
+			// Nothing would check the new generation.
+			hb.close(nil)
+			return
+		default:
+		}
+		// Unless queued, the member may be being dropped by the check of the previous generation.
+		if mb, ok := m.members[hb]; !ok || mb.index < 0 {
+			m.add(hb)
+		}
+	}
+
+	return h
+}
+
+// add starts checking h, m.mu must be held.
+func (m *Manager) add(h *heartbeat) {
+	mb := &member{h: h, next: time.Now().Add(h.checkInterval)}
+	m.members[h] = mb
+	heap.Push(&m.queue, mb)
+	if mb.index == 0 {
+		m.signal()
+	}
+
+	// Check right away once the context is done, e.g. by the parent, to move h to a terminal state
+	// without waiting for the next check.
+	context.AfterFunc(h.hctx(), func() {
+		m.reschedule(h)
+	})
+}
+
+// Len returns the number of the Heartbeats being checked.
+func (m *Manager) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.members)
+}
+
+// TimeoutStats returns the statistics of the idle timeouts of the Heartbeats checked so far.
+func (m *Manager) TimeoutStats() TimeoutStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := TimeoutStats{Count: m.timeouts, MaxOvershoot: m.overshootMax}
+	if m.timeouts > 0 {
+		stats.MeanOvershoot = m.overshootSum / time.Duration(m.timeouts)
+	}
+	return stats
+}
+
+// PauseAll pauses all the Heartbeats being checked, see Heartbeat.Pause, e.g. during a coordinated stall
+// like a database failover. The Heartbeats created until ResumeAll() start paused.
+func (m *Manager) PauseAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.paused = true
+	for h := range m.members {
+		h.paused.Store(true)
+	}
+}
+
+// ResumeAll resumes all the Heartbeats being checked, see Heartbeat.Resume.
+func (m *Manager) ResumeAll() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.paused = false
+	for h := range m.members {
+		h.resume()
+	}
+}
+
+// Close stops checking and closes the remaining Heartbeats.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	select {
+	case <-m.stop:
+		m.mu.Unlock()
+		return
+	default:
+		close(m.stop)
+	}
+	m.mu.Unlock()
+
+	<-m.exited
+
+	m.mu.Lock()
+	members := m.members
+	for _, mb := range m.queue {
+		mb.index = -1
+	}
+	m.members = nil
+	m.queue = nil
+	m.mu.Unlock()
+
+	for h := range members {
+		h.close(nil)
+		h.done()
+	}
+}
+
+// reschedule moves the check of h to now, unless it is being checked.
+func (m *Manager) reschedule(h *heartbeat) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	mb, ok := m.members[h]
+	if !ok || mb.index < 0 {
+		return
+	}
+	mb.next = time.Now()
+	heap.Fix(&m.queue, mb.index)
+	if mb.index == 0 {
+		m.signal()
+	}
+}
+
+// signal wakes the checking goroutine up to reschedule, m.mu must be held.
+func (m *Manager) signal() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (m *Manager) run() {
+	defer close(m.exited)
+
+	timer := time.NewTimer(m.interval)
+	defer timer.Stop()
+
+	var due []*member
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-m.wake:
+		case <-timer.C:
+			m.wakeups.Add(1)
+			now := time.Now()
+			due = m.due(due[:0], now)
+			m.check(due, now)
+		}
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(m.sleep())
+	}
+}
+
+// sleep returns the time until the earliest check.
+func (m *Manager) sleep() time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.queue) == 0 {
+		return m.interval
+	}
+	return time.Until(m.queue[0].next)
+}
+
+// due pops the members to be checked at now.
+func (m *Manager) due(due []*member, now time.Time) []*member {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for len(m.queue) > 0 && !m.queue[0].next.After(now.Add(m.queue[0].h.checkInterval/10)) {
+		due = append(due, heap.Pop(&m.queue).(*member))
+	}
+	return due
+}
+
+func (m *Manager) check(due []*member, now time.Time) {
+	for i, mb := range due {
+		due[i] = nil
+		h := mb.h
+		h.observeLateness(now.Sub(mb.next))
+
+		if _, ok := h.check(now); ok {
+			m.mu.Lock()
+			// The Heartbeat may have been reset and added again meanwhile.
+			if m.members[h] == mb {
+				mb.next = now.Add(h.checkInterval)
+				heap.Push(&m.queue, mb)
+			}
+			m.mu.Unlock()
+			continue
+		}
+
+		if h.hctx().Err() != nil {
+			h.done()
+		}
+		cause := h.gen.Load().timeoutErr.Load()
+
+		m.mu.Lock()
+		// The Heartbeat may have been reset and added again meanwhile.
+		if m.members[h] == mb {
+			delete(m.members, h)
+			if cause != nil && cause.Reason == ReasonIdle {
+				m.observeTimeout(max(cause.Idle-cause.Timeout, 0))
+			}
+		}
+		m.mu.Unlock()
+	}
+}
+
+// observeTimeout records the overshoot of an idle timeout, m.mu must be held.
+func (m *Manager) observeTimeout(overshoot time.Duration) {
+	m.timeouts++
+	m.overshootSum += overshoot
+	m.overshootMax = max(m.overshootMax, overshoot)
+}
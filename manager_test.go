@@ -0,0 +1,290 @@
+package heartbeat_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+func TestManager(t *testing.T) {
+	t.Parallel()
+
+	t.Run("checks and expiry", func(t *testing.T) {
+		t.Parallel()
+
+		m := heartbeat.NewManager(20 * time.Millisecond)
+		defer m.Close()
+
+		var checks, cancels atomic.Int64
+		opts := &heartbeat.Options{
+			CheckHook: func(_, _, _ time.Duration) {
+				checks.Add(1)
+			},
+			CancelHook: func(_, _, _ time.Duration) {
+				cancels.Add(1)
+			},
+		}
+
+		idle := m.New(context.Background(), 100*time.Millisecond, opts)
+		defer idle.Close()
+		busy := m.New(context.Background(), 100*time.Millisecond, opts)
+		defer busy.Close()
+		require.Equal(t, 2, m.Len())
+
+		for i := 0; i < 10; i++ {
+			busy.Beat()
+			time.Sleep(20 * time.Millisecond)
+		}
+
+		require.ErrorIs(t, context.Cause(idle.Ctx()), heartbeat.ErrTimeout)
+		require.NoError(t, busy.Ctx().Err())
+		require.Equal(t, int64(1), cancels.Load())
+		require.GreaterOrEqual(t, checks.Load(), int64(8))
+		require.Equal(t, 1, m.Len())
+
+		busy.Close()
+		require.Eventually(t, func() bool { return m.Len() == 0 }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("own check interval", func(t *testing.T) {
+		t.Parallel()
+
+		m := heartbeat.NewManager(time.Second)
+		defer m.Close()
+
+		var fast, slow atomic.Int64
+		h1 := m.New(context.Background(), time.Minute, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+			CheckHook: func(_, _, _ time.Duration) {
+				fast.Add(1)
+			},
+		})
+		defer h1.Close()
+		h2 := m.New(context.Background(), 100*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 50 * time.Millisecond,
+			CheckHook: func(_, _, _ time.Duration) {
+				slow.Add(1)
+			},
+		})
+		defer h2.Close()
+
+		<-h2.Ctx().Done()
+		require.ErrorIs(t, context.Cause(h2.Ctx()), heartbeat.ErrTimeout)
+		require.GreaterOrEqual(t, fast.Load(), int64(8))
+		require.LessOrEqual(t, slow.Load(), int64(3))
+		require.Eventually(t, func() bool { return m.Len() == 1 }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("pause all", func(t *testing.T) {
+		t.Parallel()
+
+		m := heartbeat.NewManager(10 * time.Millisecond)
+		defer m.Close()
+
+		var hs []*heartbeat.Heartbeat
+		for i := 0; i < 3; i++ {
+			h := m.New(context.Background(), 50*time.Millisecond, nil)
+			defer h.Close()
+			hs = append(hs, h)
+		}
+
+		m.PauseAll()
+		late := m.New(context.Background(), 50*time.Millisecond, nil)
+		defer late.Close()
+		hs = append(hs, late)
+
+		time.Sleep(150 * time.Millisecond)
+		for _, h := range hs {
+			require.True(t, h.Paused())
+			require.NoError(t, h.Ctx().Err())
+		}
+
+		m.ResumeAll()
+		for _, h := range hs {
+			require.False(t, h.Paused())
+			require.True(t, h.Healthy(), "the time paused doesn't count")
+		}
+		for _, h := range hs {
+			<-h.Ctx().Done()
+			require.ErrorIs(t, context.Cause(h.Ctx()), heartbeat.ErrTimeout)
+		}
+	})
+
+	t.Run("parent cancelled", func(t *testing.T) {
+		t.Parallel()
+
+		m := heartbeat.NewManager(time.Hour)
+		defer m.Close()
+
+		var hooks atomic.Int64
+		parent, cancel := context.WithCancel(context.Background())
+		h := m.New(parent, time.Hour, &heartbeat.Options{
+			ParentCancelHook: func(_, _, _ time.Duration) {
+				hooks.Add(1)
+			},
+		})
+		defer h.Close()
+
+		cancel()
+		require.Eventually(t, func() bool {
+			return h.State() == heartbeat.StateCancelled && m.Len() == 0
+		}, time.Second, 5*time.Millisecond)
+		require.Equal(t, int64(1), hooks.Load())
+	})
+
+	t.Run("close", func(t *testing.T) {
+		t.Parallel()
+
+		m := heartbeat.NewManager(time.Second)
+		h := m.New(context.Background(), time.Minute, nil)
+
+		m.Close()
+		m.Close()
+
+		require.ErrorIs(t, context.Cause(h.Ctx()), context.Canceled)
+		require.Equal(t, heartbeat.StateClosed, h.State())
+		require.Panics(t, func() {
+			m.New(context.Background(), time.Minute, nil)
+		})
+	})
+}
+
+func TestManager_starvation(t *testing.T) {
+	t.Parallel()
+
+	m := heartbeat.NewManager(20 * time.Millisecond)
+	defer m.Close()
+
+	// The slow hook of one Heartbeat delays the checks of the others.
+	slow := m.New(context.Background(), time.Minute, &heartbeat.Options{
+		CheckHook: func(_, _, _ time.Duration) {
+			time.Sleep(40 * time.Millisecond)
+		},
+	})
+	defer slow.Close()
+	var late atomic.Int64
+	h := m.New(context.Background(), time.Minute, &heartbeat.Options{
+		CheckInterval:       25 * time.Millisecond,
+		StarvationThreshold: 10 * time.Millisecond,
+		StarvationHook: func(d time.Duration) {
+			late.Store(int64(d))
+		},
+	})
+	defer h.Close()
+
+	require.Eventually(t, func() bool {
+		return late.Load() > int64(10*time.Millisecond)
+	}, time.Second, 5*time.Millisecond)
+	stats := h.Stats()
+	require.Greater(t, stats.MaxCheckLateness, 10*time.Millisecond)
+	require.LessOrEqual(t, stats.MeanCheckLateness, stats.MaxCheckLateness)
+}
+
+func TestManager_TimeoutStats(t *testing.T) {
+	t.Parallel()
+
+	m := heartbeat.NewManager(50 * time.Millisecond)
+	defer m.Close()
+	require.Zero(t, m.TimeoutStats())
+
+	// The coarse check interval makes the timeouts overshoot.
+	idle := m.New(context.Background(), 20*time.Millisecond, nil)
+	defer idle.Close()
+	forced := m.New(context.Background(), time.Minute, nil)
+	defer forced.Close()
+	require.NoError(t, forced.ForceExpire(nil))
+
+	<-idle.Ctx().Done()
+	require.Eventually(t, func() bool { return m.Len() == 0 }, time.Second, 10*time.Millisecond)
+
+	stats := m.TimeoutStats()
+	require.Equal(t, uint64(1), stats.Count)
+	require.Greater(t, stats.MaxOvershoot, 10*time.Millisecond)
+	require.LessOrEqual(t, stats.MaxOvershoot, 50*time.Millisecond)
+	require.Equal(t, stats.MaxOvershoot, stats.MeanOvershoot)
+}
+
+// BenchmarkHeartbeats compares the memory used by 10k running Heartbeats and the wakeups of the checking goroutines,
+// with a goroutine per Heartbeat, suspended with Options.SuspendWhenIdle or checked by a Manager.
+func BenchmarkHeartbeats(b *testing.B) {
+	const (
+		n      = 10_000
+		period = 100 * time.Millisecond
+	)
+
+	bench := func(b *testing.B, newHeartbeat func() *heartbeat.Heartbeat, wakeups func([]*heartbeat.Heartbeat) uint64) {
+		b.ReportAllocs()
+		goroutines := runtime.NumGoroutine()
+
+		for i := 0; i < b.N; i++ {
+			var before, after runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+
+			hs := make([]*heartbeat.Heartbeat, n)
+			for j := range hs {
+				hs[j] = newHeartbeat()
+			}
+			start := wakeups(hs)
+			time.Sleep(period) // let the checks run
+
+			runtime.ReadMemStats(&after)
+			b.ReportMetric(float64(after.StackInuse+after.HeapInuse-before.StackInuse-before.HeapInuse)/n, "bytes/heartbeat")
+			b.ReportMetric(float64(wakeups(hs)-start)/period.Seconds(), "wakeups/s")
+
+			for _, h := range hs {
+				h.Close()
+			}
+			for runtime.NumGoroutine() > goroutines {
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}
+
+	opts := &heartbeat.Options{CheckInterval: 10 * time.Millisecond}
+
+	b.Run("goroutines", func(b *testing.B) {
+		bench(b, func() *heartbeat.Heartbeat {
+			return heartbeat.New(context.Background(), time.Minute, opts)
+		}, func(hs []*heartbeat.Heartbeat) uint64 {
+			// Every check is a wakeup of its goroutine.
+			var checks uint64
+			for _, h := range hs {
+				checks += h.Stats().Checks
+			}
+			return checks
+		})
+	})
+
+	b.Run("dormant", func(b *testing.B) {
+		dormant := &heartbeat.Options{CheckInterval: opts.CheckInterval, SuspendWhenIdle: true}
+		bench(b, func() *heartbeat.Heartbeat {
+			return heartbeat.New(context.Background(), opts.CheckInterval, dormant)
+		}, func(hs []*heartbeat.Heartbeat) uint64 {
+			// Measure once they are suspended.
+			var checks uint64
+			for _, h := range hs {
+				for !h.Suspended() {
+					time.Sleep(time.Millisecond)
+				}
+				checks += h.Stats().Checks
+			}
+			return checks
+		})
+	})
+
+	b.Run("manager", func(b *testing.B) {
+		m := heartbeat.NewManager(10 * time.Millisecond)
+		defer m.Close()
+
+		bench(b, func() *heartbeat.Heartbeat {
+			return m.New(context.Background(), time.Minute, opts)
+		}, func([]*heartbeat.Heartbeat) uint64 {
+			return m.Wakeups()
+		})
+	})
+}
@@ -0,0 +1,33 @@
+package heartbeat
+
+import "time"
+
+// Metrics receives the telemetry of the Heartbeats, see Options.Metrics, so that any metrics system
+// can be adapted behind it. The methods are called by the checks, concurrently for different Heartbeats.
+type Metrics interface {
+	// IncCounter increments the counter by one.
+	IncCounter(name string)
+	// SetGauge sets the gauge to value.
+	SetGauge(name string, value float64)
+	// ObserveDuration records d in the distribution, e.g. a histogram.
+	ObserveDuration(name string, d time.Duration)
+}
+
+// The names of the metrics reported to Metrics.
+const (
+	// MetricChecks counts the timeout checks.
+	MetricChecks = "heartbeat_checks_total"
+	// MetricTimeouts counts the expiries.
+	MetricTimeouts = "heartbeat_timeouts_total"
+	// MetricIdle is the time passed since the last beat at the last check, in seconds.
+	MetricIdle = "heartbeat_idle_seconds"
+	// MetricCheckDuration is the distribution of the check durations, hooks included.
+	MetricCheckDuration = "heartbeat_check_duration"
+)
+
+// NopMetrics discards the metrics. Embed it to implement a part of Metrics only.
+type NopMetrics struct{}
+
+func (NopMetrics) IncCounter(string)                     {}
+func (NopMetrics) SetGauge(string, float64)              {}
+func (NopMetrics) ObserveDuration(string, time.Duration) {}
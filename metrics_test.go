@@ -0,0 +1,92 @@
+package heartbeat_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+// recordingMetrics records the metrics reported, only the counters and the gauges.
+type recordingMetrics struct {
+	heartbeat.NopMetrics
+
+	mu       sync.Mutex
+	counters map[string]int
+	gauges   map[string]float64
+}
+
+func (m *recordingMetrics) IncCounter(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.counters[name]++
+}
+
+func (m *recordingMetrics) SetGauge(name string, value float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gauges[name] = value
+}
+
+func TestOptions_Metrics(t *testing.T) {
+	t.Parallel()
+
+	m := &recordingMetrics{counters: make(map[string]int), gauges: make(map[string]float64)}
+	h := heartbeat.New(context.Background(), 100*time.Millisecond, &heartbeat.Options{
+		CheckInterval: 10 * time.Millisecond,
+		Metrics:       m,
+	})
+	defer h.Close()
+
+	time.Sleep(50 * time.Millisecond)
+	m.mu.Lock()
+	require.GreaterOrEqual(t, m.counters[heartbeat.MetricChecks], 3)
+	require.Greater(t, m.gauges[heartbeat.MetricIdle], 0.0)
+	require.Zero(t, m.counters[heartbeat.MetricTimeouts])
+	m.mu.Unlock()
+
+	<-h.Ctx().Done()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	require.Equal(t, 1, m.counters[heartbeat.MetricTimeouts])
+	require.GreaterOrEqual(t, m.gauges[heartbeat.MetricIdle], 0.09)
+}
+
+func TestOptions_Metrics_checkDuration(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var durations []time.Duration
+	h := heartbeat.New(context.Background(), time.Minute, &heartbeat.Options{
+		CheckInterval: 10 * time.Millisecond,
+		CheckHook: func(_, _, _ time.Duration) {
+			time.Sleep(5 * time.Millisecond)
+		},
+		Metrics: durationMetrics(func(name string, d time.Duration) {
+			if name == heartbeat.MetricCheckDuration {
+				mu.Lock()
+				durations = append(durations, d)
+				mu.Unlock()
+			}
+		}),
+	})
+	defer h.Close()
+
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	require.NotEmpty(t, durations)
+	require.GreaterOrEqual(t, durations[0], 5*time.Millisecond)
+}
+
+// durationMetrics reports the durations only.
+type durationMetrics func(name string, d time.Duration)
+
+func (durationMetrics) IncCounter(string)        {}
+func (durationMetrics) SetGauge(string, float64) {}
+
+func (f durationMetrics) ObserveDuration(name string, d time.Duration) {
+	f(name, d)
+}
@@ -0,0 +1,296 @@
+package heartbeat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// DefaultOptions returns the Options with the defaults filled in, e.g. to be layered with Merge.
+func DefaultOptions() *Options {
+	return &Options{
+		CheckInterval:     DefaultCheckInterval,
+		CancelHookTimeout: DefaultCancelHookTimeout,
+	}
+}
+
+// Merge returns a copy of o with the non-zero fields of other overlaid, e.g.
+//
+//	opts := heartbeat.DefaultOptions().Merge(libraryOpts).Merge(userOpts)
+//
+// The zero fields of other, like false or nil hooks, don't clobber the fields set in o.
+// Either of o and other may be nil. Like Clone, the copy doesn't share the Schedule with o or other.
+func (o *Options) Merge(other *Options) *Options {
+	var merged Options
+	if o != nil {
+		merged = *o
+	}
+	if other == nil {
+		return merged.Clone()
+	}
+
+	dst, src := reflect.ValueOf(&merged).Elem(), reflect.ValueOf(other).Elem()
+	for i := 0; i < src.NumField(); i++ {
+		if f := src.Field(i); !f.IsZero() {
+			dst.Field(i).Set(f)
+		}
+	}
+	return merged.Clone()
+}
+
+// Validate reports the invalid fields of o, e.g. to check the Options loaded from a config file
+// before creating any Heartbeat. NewE returns the same error. A nil o is valid.
+// The zero fields stand for the defaults, the negative durations and counts are invalid.
+// For compatibility, New only rejects an invalid TimeoutScale or Schedule.Interval, the other invalid
+// fields are ignored by it, e.g. a negative CheckInterval stands for the default.
+func (o *Options) Validate() error {
+	return o.validate(true)
+}
+
+// validate is Validate, only the fields New has always rejected are checked unless strict.
+func (o *Options) validate(strict bool) error {
+	if o == nil {
+		return nil
+	}
+
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("heartbeat: "+format, args...))
+	}
+
+	if o.TimeoutScale != 0 && !validScale(o.TimeoutScale) {
+		invalid("TimeoutScale %v is not positive and finite", o.TimeoutScale)
+	}
+	if o.Schedule != nil && o.Schedule.Interval <= 0 {
+		invalid("non-positive Schedule.Interval %s", o.Schedule.Interval)
+	}
+	if !strict {
+		return errors.Join(errs...)
+	}
+
+	for _, d := range []struct {
+		name  string
+		value time.Duration
+	}{
+		{"CheckInterval", o.CheckInterval},
+		{"CancelHookTimeout", o.CancelHookTimeout},
+		{"CleanupGrace", o.CleanupGrace},
+		{"StarvationThreshold", o.StarvationThreshold},
+		{"RateWindow", o.RateWindow},
+		{"MaxLifetime", o.MaxLifetime},
+		{"EscalationDelay", o.EscalationDelay},
+		{"RequireFirstBeatWithin", o.RequireFirstBeatWithin},
+		{"DrainTimeout", o.DrainTimeout},
+	} {
+		if d.value < 0 {
+			invalid("negative %s %s", d.name, d.value)
+		}
+	}
+	if o.BeatTokens < 0 {
+		invalid("negative BeatTokens %d", o.BeatTokens)
+	}
+	if o.ConsecutiveMissesToCancel < 0 {
+		invalid("negative ConsecutiveMissesToCancel %d", o.ConsecutiveMissesToCancel)
+	}
+	if !(o.NearMissFraction >= 0 && o.NearMissFraction < 1) {
+		invalid("NearMissFraction %v is out of [0, 1)", o.NearMissFraction)
+	}
+	if o.NearMissHook != nil && o.NearMissFraction == 0 {
+		invalid("NearMissHook requires NearMissFraction")
+	}
+	if o.SuspendWhenIdle && o.DontCancel {
+		invalid("SuspendWhenIdle conflicts with DontCancel")
+	}
+	if o.SuspendWhenIdle && o.ConsecutiveMissesToCancel > 1 {
+		invalid("SuspendWhenIdle conflicts with ConsecutiveMissesToCancel")
+	}
+	if s := o.Schedule; s != nil {
+		if s.Interval > 0 && (s.Tolerance < 0 || s.Tolerance >= s.Interval) {
+			invalid("Schedule.Tolerance %s is out of [0, Schedule.Interval)", s.Tolerance)
+		}
+		if s.MaxMissed < 0 {
+			invalid("negative Schedule.MaxMissed %d", s.MaxMissed)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Clone returns a copy of o not sharing the Schedule with it. The hooks, Metrics, ForwardBeatsTo
+// and CancelFunc are shared. Clone of nil is nil.
+func (o *Options) Clone() *Options {
+	if o == nil {
+		return nil
+	}
+
+	c := *o
+	if o.Schedule != nil {
+		s := *o.Schedule
+		c.Schedule = &s
+	}
+	return &c
+}
+
+// Defaulted returns a copy of o with the defaults in effect filled in, e.g. to see the check interval
+// New would use. The check interval of the Heartbeats created by a Manager is the interval of the Manager,
+// if CheckInterval is zero. A nil o stands for the zero Options.
+func (o *Options) Defaulted() *Options {
+	d := o.Clone()
+	if d == nil {
+		d = &Options{}
+	}
+
+	if d.TimeoutScale == 0 {
+		d.TimeoutScale = TimeoutScale()
+	}
+	if d.CheckInterval <= 0 {
+		d.CheckInterval = DefaultCheckInterval
+	}
+	if d.CancelHookTimeout <= 0 {
+		d.CancelHookTimeout = DefaultCancelHookTimeout
+	}
+	if d.StarvationThreshold <= 0 {
+		d.StarvationThreshold = d.CheckInterval
+	}
+	if d.RateHook != nil && d.RateWindow <= 0 {
+		d.RateWindow = d.CheckInterval
+	}
+	if d.BeatTokens <= 0 {
+		d.BeatTokens = DefaultBeatTokens
+	}
+	if d.ConsecutiveMissesToCancel <= 0 {
+		d.ConsecutiveMissesToCancel = 1
+	}
+	if d.Schedule != nil && d.Schedule.MaxMissed <= 0 {
+		d.Schedule.MaxMissed = 1
+	}
+	return d
+}
+
+// Option configures the Heartbeat created by NewWith.
+type Option func(c *optionConfig)
+
+// optionConfig is the Options built by the Option functions, with the errors of the invalid arguments.
+type optionConfig struct {
+	opts Options
+	errs []error
+}
+
+func (c *optionConfig) invalid(format string, args ...any) {
+	c.errs = append(c.errs, fmt.Errorf("heartbeat: "+format, args...))
+}
+
+// NewWith is the same as NewE configured with the Option functions rather than Options, e.g.
+//
+//	h := heartbeat.NewWith(ctx, time.Minute, heartbeat.WithName("upload"), heartbeat.WithCheckInterval(time.Second))
+//
+// Unlike the zero fields of Options, an Option given a zero duration or a nil hook is an error.
+// It panics with the errors of the Option functions and of NewE.
+func NewWith(ctx context.Context, timeout time.Duration, opts ...Option) *Heartbeat {
+	var c optionConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if len(c.errs) > 0 {
+		panic(errors.Join(c.errs...))
+	}
+	h, err := NewE(ctx, timeout, &c.opts)
+	if err != nil {
+		panic(err)
+	}
+	return h
+}
+
+// WithOptions overlays the non-zero fields of opts, see Options.Merge, e.g. for the options without
+// a dedicated Option function.
+func WithOptions(opts *Options) Option {
+	return func(c *optionConfig) {
+		c.opts = *c.opts.Merge(opts)
+	}
+}
+
+// WithName sets Options.Name.
+func WithName(name string) Option {
+	return func(c *optionConfig) {
+		c.opts.Name = name
+	}
+}
+
+// WithCheckInterval sets Options.CheckInterval.
+func WithCheckInterval(interval time.Duration) Option {
+	return func(c *optionConfig) {
+		if interval <= 0 {
+			c.invalid("non-positive check interval %s", interval)
+		}
+		c.opts.CheckInterval = interval
+	}
+}
+
+// WithCheckHook sets Options.CheckHook.
+func WithCheckHook(hook HookFn) Option {
+	return func(c *optionConfig) {
+		if hook == nil {
+			c.invalid("nil check hook")
+		}
+		c.opts.CheckHook = hook
+	}
+}
+
+// WithCancelHook sets Options.CancelHook.
+func WithCancelHook(hook HookFn) Option {
+	return func(c *optionConfig) {
+		if hook == nil {
+			c.invalid("nil cancel hook")
+		}
+		c.opts.CancelHook = hook
+	}
+}
+
+// WithBeatHook sets Options.BeatHook.
+func WithBeatHook(hook BeatHookFn) Option {
+	return func(c *optionConfig) {
+		if hook == nil {
+			c.invalid("nil beat hook")
+		}
+		c.opts.BeatHook = hook
+	}
+}
+
+// WithStateHook sets Options.StateHook.
+func WithStateHook(hook func(from, to State)) Option {
+	return func(c *optionConfig) {
+		if hook == nil {
+			c.invalid("nil state hook")
+		}
+		c.opts.StateHook = hook
+	}
+}
+
+// WithMetrics sets Options.Metrics.
+func WithMetrics(metrics Metrics) Option {
+	return func(c *optionConfig) {
+		if metrics == nil {
+			c.invalid("nil metrics")
+		}
+		c.opts.Metrics = metrics
+	}
+}
+
+// WithMaxLifetime sets Options.MaxLifetime.
+func WithMaxLifetime(lifetime time.Duration) Option {
+	return func(c *optionConfig) {
+		if lifetime <= 0 {
+			c.invalid("non-positive max lifetime %s", lifetime)
+		}
+		c.opts.MaxLifetime = lifetime
+	}
+}
+
+// WithDontCancel sets Options.DontCancel.
+func WithDontCancel() Option {
+	return func(c *optionConfig) {
+		c.opts.DontCancel = true
+	}
+}
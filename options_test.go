@@ -0,0 +1,220 @@
+package heartbeat_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+func TestDefaultOptions(t *testing.T) {
+	t.Parallel()
+
+	opts := heartbeat.DefaultOptions()
+	require.Equal(t, heartbeat.DefaultCheckInterval, opts.CheckInterval)
+	require.Equal(t, heartbeat.DefaultCancelHookTimeout, opts.CancelHookTimeout)
+}
+
+func TestOptions_Merge(t *testing.T) {
+	t.Parallel()
+
+	var called string
+	base := &heartbeat.Options{
+		CheckInterval: time.Second,
+		DontCancel:    true,
+		CheckHook: func(_, _, _ time.Duration) {
+			called = "base"
+		},
+	}
+	merged := base.Merge(&heartbeat.Options{
+		CheckInterval: 10 * time.Millisecond,
+		MaxLifetime:   time.Minute,
+	})
+
+	require.Equal(t, 10*time.Millisecond, merged.CheckInterval)
+	require.Equal(t, time.Minute, merged.MaxLifetime)
+	require.True(t, merged.DontCancel)
+	merged.CheckHook(0, 0, 0)
+	require.Equal(t, "base", called)
+
+	merged = merged.Merge(&heartbeat.Options{
+		CheckHook: func(_, _, _ time.Duration) {
+			called = "other"
+		},
+	})
+	merged.CheckHook(0, 0, 0)
+	require.Equal(t, "other", called)
+
+	// The receiver is not modified.
+	require.Equal(t, time.Second, base.CheckInterval)
+	require.Zero(t, base.MaxLifetime)
+
+	var nilOpts *heartbeat.Options
+	require.Equal(t, time.Minute, nilOpts.Merge(merged).MaxLifetime)
+	require.Equal(t, time.Minute, merged.Merge(nil).MaxLifetime)
+	require.NotSame(t, merged, merged.Merge(nil))
+
+	// The Schedule is not shared, changing it in the copy leaves the original alone.
+	schedule := &heartbeat.Schedule{Interval: time.Minute}
+	for _, merged := range []*heartbeat.Options{
+		(&heartbeat.Options{Schedule: schedule}).Merge(nil),
+		base.Merge(&heartbeat.Options{Schedule: schedule}),
+	} {
+		require.NotSame(t, schedule, merged.Schedule)
+		merged.Schedule.Tolerance = time.Second
+	}
+	require.Equal(t, heartbeat.Schedule{Interval: time.Minute}, *schedule)
+}
+
+func TestOptions_Validate(t *testing.T) {
+	t.Parallel()
+
+	var nilOpts *heartbeat.Options
+	require.NoError(t, nilOpts.Validate())
+	require.NoError(t, (&heartbeat.Options{}).Validate())
+	require.NoError(t, heartbeat.DefaultOptions().Validate())
+
+	tests := []struct {
+		name string
+		opts heartbeat.Options
+		err  string
+		// rejected by New too, the other fields are ignored by it for compatibility
+		always bool
+	}{
+		{"negative check interval", heartbeat.Options{CheckInterval: -time.Second}, "negative CheckInterval -1s", false},
+		{"negative lifetime", heartbeat.Options{MaxLifetime: -time.Minute}, "negative MaxLifetime -1m0s", false},
+		{"negative scale", heartbeat.Options{TimeoutScale: -1}, "TimeoutScale -1 is not positive and finite", true},
+		{"negative tokens", heartbeat.Options{BeatTokens: -1}, "negative BeatTokens -1", false},
+		{"near miss fraction", heartbeat.Options{NearMissFraction: 1}, "NearMissFraction 1 is out of [0, 1)", false},
+		{"near miss hook", heartbeat.Options{NearMissHook: func(time.Duration) {}}, "NearMissHook requires NearMissFraction", false},
+		{"schedule interval", heartbeat.Options{Schedule: &heartbeat.Schedule{}}, "non-positive Schedule.Interval 0s", true},
+		{
+			"schedule tolerance",
+			heartbeat.Options{Schedule: &heartbeat.Schedule{Interval: time.Minute, Tolerance: time.Minute}},
+			"Schedule.Tolerance 1m0s is out of [0, Schedule.Interval)",
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			require.EqualError(t, err, "heartbeat: "+tt.err)
+			_, newErr := heartbeat.NewE(context.Background(), time.Second, &tt.opts)
+			require.Equal(t, err, newErr)
+
+			if tt.always {
+				require.PanicsWithError(t, err.Error(), func() {
+					heartbeat.New(context.Background(), time.Second, &tt.opts)
+				})
+				return
+			}
+			h := heartbeat.New(context.Background(), time.Second, &tt.opts)
+			h.Close()
+		})
+	}
+
+	// All the violations are reported.
+	err := (&heartbeat.Options{CheckInterval: -1, DrainTimeout: -1}).Validate()
+	require.ErrorContains(t, err, "negative CheckInterval")
+	require.ErrorContains(t, err, "negative DrainTimeout")
+}
+
+func TestOptions_Clone(t *testing.T) {
+	t.Parallel()
+
+	var nilOpts *heartbeat.Options
+	require.Nil(t, nilOpts.Clone())
+
+	base := &heartbeat.Options{
+		Name:     "base",
+		Schedule: &heartbeat.Schedule{Interval: time.Minute},
+	}
+	clone := base.Clone()
+	clone.Name = "clone"
+	clone.Schedule.Interval = time.Hour
+
+	require.Equal(t, "base", base.Name)
+	require.Equal(t, time.Minute, base.Schedule.Interval)
+}
+
+func TestOptions_Defaulted(t *testing.T) {
+	t.Parallel()
+
+	var nilOpts *heartbeat.Options
+	defaulted := nilOpts.Defaulted()
+	require.Equal(t, heartbeat.DefaultCheckInterval, defaulted.CheckInterval)
+	require.Equal(t, heartbeat.DefaultCancelHookTimeout, defaulted.CancelHookTimeout)
+	require.Equal(t, heartbeat.DefaultCheckInterval, defaulted.StarvationThreshold)
+	require.Equal(t, heartbeat.DefaultBeatTokens, defaulted.BeatTokens)
+	require.Equal(t, 1, defaulted.ConsecutiveMissesToCancel)
+	require.Equal(t, heartbeat.TimeoutScale(), defaulted.TimeoutScale)
+	require.Zero(t, defaulted.RateWindow)
+
+	opts := &heartbeat.Options{
+		CheckInterval: time.Second,
+		RateHook:      func(float64) {},
+		Schedule:      &heartbeat.Schedule{Interval: time.Minute},
+	}
+	defaulted = opts.Defaulted()
+	require.Equal(t, time.Second, defaulted.StarvationThreshold)
+	require.Equal(t, time.Second, defaulted.RateWindow)
+	require.Equal(t, 1, defaulted.Schedule.MaxMissed)
+
+	// The receiver is not modified.
+	require.Zero(t, opts.RateWindow)
+	require.Zero(t, opts.Schedule.MaxMissed)
+}
+
+func TestNewWith(t *testing.T) {
+	t.Parallel()
+
+	var states []heartbeat.State
+	h := heartbeat.NewWith(context.Background(), 50*time.Millisecond,
+		heartbeat.WithName("upload"),
+		heartbeat.WithCheckInterval(10*time.Millisecond),
+		heartbeat.WithStateHook(func(_, to heartbeat.State) {
+			states = append(states, to)
+		}),
+		heartbeat.WithOptions(&heartbeat.Options{TrackCheckLatency: true}),
+	)
+	defer h.Close()
+
+	<-h.Ctx().Done()
+	h.Close()
+	require.Equal(t, "upload", h.Name())
+	require.ErrorContains(t, context.Cause(h.Ctx()), `timeout of "upload"`)
+	require.Positive(t, h.Stats().CheckLatencyP50)
+	require.Equal(t, []heartbeat.State{heartbeat.StateExpired}, states)
+
+	tests := []struct {
+		name string
+		opts []heartbeat.Option
+		err  string
+	}{
+		{
+			"zero check interval",
+			[]heartbeat.Option{heartbeat.WithCheckInterval(0)},
+			"heartbeat: non-positive check interval 0s",
+		},
+		{
+			"nil hooks",
+			[]heartbeat.Option{heartbeat.WithCheckHook(nil), heartbeat.WithCancelHook(nil)},
+			"heartbeat: nil check hook\nheartbeat: nil cancel hook",
+		},
+		{
+			"conflicting options",
+			[]heartbeat.Option{heartbeat.WithDontCancel(), heartbeat.WithOptions(&heartbeat.Options{SuspendWhenIdle: true})},
+			"heartbeat: SuspendWhenIdle conflicts with DontCancel",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.PanicsWithError(t, tt.err, func() {
+				heartbeat.NewWith(context.Background(), time.Second, tt.opts...)
+			})
+		})
+	}
+}
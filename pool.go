@@ -0,0 +1,176 @@
+package heartbeat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Decision tells Pool what to do when the Heartbeat of a worker expires.
+type Decision int
+
+const (
+	// Restart starts the worker again in the same slot.
+	Restart Decision = iota
+	// Ignore leaves the slot empty, the expiry is reported by Wait().
+	Ignore
+	// CancelAll cancels all the workers of the Pool.
+	CancelAll
+)
+
+// WorkerFn is the signature of Pool workers.
+// ctx is the context controlled by the worker's Heartbeat, beat reports the worker's progress.
+type WorkerFn func(ctx context.Context, beat func()) error
+
+// PoolOptions defines optional parameters of Pool.
+type PoolOptions struct {
+	// Options are the options of the worker Heartbeats.
+	Options *Options
+	// OnWorkerExpired is called when the Heartbeat of the worker at index expires, after the worker has returned.
+	// cause is the cancellation cause of the worker context. Restart is the default decision.
+	OnWorkerExpired func(index int, cause error) Decision
+	// RestartBackoff is the delay before restarting an expired worker. It doubles on every consecutive restart
+	// of the same worker without a beat in between, up to MaxRestartBackoff.
+	RestartBackoff time.Duration
+	// MaxRestartBackoff caps RestartBackoff, DefaultMaxRestartBackoff by default.
+	MaxRestartBackoff time.Duration
+}
+
+const (
+	// DefaultMaxRestartBackoff is the default maximum delay before restarting an expired Pool worker.
+	DefaultMaxRestartBackoff = time.Minute
+)
+
+// Pool supervises a fixed number of workers with a Heartbeat per worker, so that a wedged worker
+// can be restarted without tearing down the whole Pool.
+type Pool struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	n       int
+	timeout time.Duration
+	opts    PoolOptions
+
+	started bool
+	wg      sync.WaitGroup
+
+	mu   sync.Mutex
+	errs []error
+}
+
+// NewPool creates a Pool of n workers, each supervised by a Heartbeat with the given timeout.
+// Cancelling ctx stops all the workers.
+func NewPool(ctx context.Context, n int, timeout time.Duration, opts *PoolOptions) *Pool {
+	if n <= 0 {
+		panic("positive number of workers is required")
+	}
+	if timeout <= 0 {
+		panic("positive timeout is required")
+	}
+
+	pctx, cancel := context.WithCancel(ctx)
+	p := &Pool{
+		ctx:     pctx,
+		cancel:  cancel,
+		n:       n,
+		timeout: timeout,
+	}
+	if opts != nil {
+		p.opts = *opts
+	}
+	if p.opts.MaxRestartBackoff <= 0 {
+		p.opts.MaxRestartBackoff = DefaultMaxRestartBackoff
+	}
+
+	return p
+}
+
+// Run starts the workers. Workers must return once their context is done, an expired worker is restarted only
+// after it has returned. A worker returning by itself is not restarted, its error is reported by Wait().
+func (p *Pool) Run(worker WorkerFn) {
+	if p.started {
+		panic("pool is already running")
+	}
+	p.started = true
+
+	p.wg.Add(p.n)
+	for i := 0; i < p.n; i++ {
+		go p.supervise(i, worker)
+	}
+}
+
+// Wait waits for all the workers to finish and returns their errors joined,
+// including the expiries that ended the workers.
+func (p *Pool) Wait() error {
+	p.wg.Wait()
+	p.cancel()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return errors.Join(p.errs...)
+}
+
+func (p *Pool) supervise(i int, worker WorkerFn) {
+	defer p.wg.Done()
+
+	backoff := p.opts.RestartBackoff
+
+	for {
+		h := New(p.ctx, p.timeout, p.opts.Options)
+		err := worker(h.Ctx(), h.Beat)
+		h.Close()
+
+		if p.ctx.Err() != nil {
+			// The Pool is cancelled, the context errors are expected.
+			if err != nil && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
+				p.fail(i, err)
+			}
+			return
+		}
+
+		if h.State() != StateExpired {
+			if err != nil {
+				p.fail(i, err)
+			}
+			return
+		}
+
+		cause := context.Cause(h.Ctx())
+		decision := Restart
+		if p.opts.OnWorkerExpired != nil {
+			decision = p.opts.OnWorkerExpired(i, cause)
+		}
+
+		switch decision {
+		case Ignore:
+			p.fail(i, cause)
+			return
+		case CancelAll:
+			p.fail(i, cause)
+			p.cancel()
+			return
+		}
+
+		if h.Stats().Beats > 0 {
+			backoff = p.opts.RestartBackoff
+		}
+
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > p.opts.MaxRestartBackoff {
+			backoff = p.opts.MaxRestartBackoff
+		}
+	}
+}
+
+func (p *Pool) fail(i int, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.errs = append(p.errs, fmt.Errorf("heartbeat: worker %d: %w", i, err))
+}
@@ -0,0 +1,153 @@
+package heartbeat_test
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+func TestPool(t *testing.T) {
+	t.Parallel()
+
+	opts := &heartbeat.Options{CheckInterval: 10 * time.Millisecond}
+
+	// stall blocks until ctx is done and returns its error.
+	stall := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	t.Run("restart only the expired worker", func(t *testing.T) {
+		t.Parallel()
+
+		var starts [3]atomic.Int64
+		var expiries atomic.Int64
+
+		p := heartbeat.NewPool(context.Background(), 3, 100*time.Millisecond, &heartbeat.PoolOptions{
+			Options: opts,
+			OnWorkerExpired: func(index int, cause error) heartbeat.Decision {
+				expiries.Add(1)
+				assert.ErrorIs(t, cause, heartbeat.ErrTimeout)
+				return heartbeat.Restart
+			},
+		})
+
+		var index atomic.Int64
+		p.Run(func(ctx context.Context, beat func()) error {
+			// The first starts take the indexes 0-2, the first start of the last one stalls and the restart takes 2.
+			i := index.Add(1) - 1
+			if i >= 3 {
+				i = 2
+			}
+			if starts[i].Add(1) == 1 && i == 2 {
+				return stall(ctx)
+			}
+
+			for j := 0; j < 5; j++ {
+				beat()
+				time.Sleep(50 * time.Millisecond)
+			}
+			return nil
+		})
+
+		require.NoError(t, p.Wait())
+		assert.Equal(t, int64(1), starts[0].Load())
+		assert.Equal(t, int64(1), starts[1].Load())
+		assert.Equal(t, int64(2), starts[2].Load())
+		assert.Equal(t, int64(1), expiries.Load())
+	})
+
+	t.Run("ignore", func(t *testing.T) {
+		t.Parallel()
+
+		var starts atomic.Int64
+
+		p := heartbeat.NewPool(context.Background(), 2, 100*time.Millisecond, &heartbeat.PoolOptions{
+			Options: opts,
+			OnWorkerExpired: func(int, error) heartbeat.Decision {
+				return heartbeat.Ignore
+			},
+		})
+		p.Run(func(ctx context.Context, beat func()) error {
+			starts.Add(1)
+			return stall(ctx)
+		})
+
+		err := p.Wait()
+		require.ErrorIs(t, err, heartbeat.ErrTimeout)
+		require.ErrorContains(t, err, "worker 0")
+		require.ErrorContains(t, err, "worker 1")
+		require.Equal(t, int64(2), starts.Load())
+	})
+
+	t.Run("cancel all", func(t *testing.T) {
+		t.Parallel()
+
+		p := heartbeat.NewPool(context.Background(), 2, 100*time.Millisecond, &heartbeat.PoolOptions{
+			Options: opts,
+			OnWorkerExpired: func(int, error) heartbeat.Decision {
+				return heartbeat.CancelAll
+			},
+		})
+
+		var index atomic.Int64
+		p.Run(func(ctx context.Context, beat func()) error {
+			if index.Add(1) == 1 {
+				return stall(ctx)
+			}
+			for ctx.Err() == nil {
+				beat()
+				time.Sleep(10 * time.Millisecond)
+			}
+			return ctx.Err()
+		})
+
+		start := time.Now()
+		err := p.Wait()
+		require.ErrorIs(t, err, heartbeat.ErrTimeout)
+		require.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("worker errors, restart backoff", func(t *testing.T) {
+		t.Parallel()
+
+		errWorker := errors.New("worker failed")
+		var starts []time.Time
+
+		p := heartbeat.NewPool(context.Background(), 1, 50*time.Millisecond, &heartbeat.PoolOptions{
+			Options:        opts,
+			RestartBackoff: 100 * time.Millisecond,
+		})
+		p.Run(func(ctx context.Context, beat func()) error {
+			starts = append(starts, time.Now())
+			if len(starts) < 3 {
+				return stall(ctx)
+			}
+			return errWorker
+		})
+
+		require.ErrorIs(t, p.Wait(), errWorker)
+		require.Len(t, starts, 3)
+		// Expiry after 50-60ms, then backoff of 100ms and 200ms.
+		assert.InDelta(t, 150*time.Millisecond, starts[1].Sub(starts[0]), float64(30*time.Millisecond))
+		assert.InDelta(t, 250*time.Millisecond, starts[2].Sub(starts[1]), float64(30*time.Millisecond))
+	})
+
+	t.Run("parent cancelled", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		p := heartbeat.NewPool(ctx, 2, time.Second, &heartbeat.PoolOptions{Options: opts})
+		p.Run(func(ctx context.Context, beat func()) error {
+			return stall(ctx)
+		})
+
+		cancel()
+		require.NoError(t, p.Wait())
+	})
+}
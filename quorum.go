@@ -0,0 +1,161 @@
+package heartbeat
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Quorum is alive while at least k of its named sources have been beaten within the timeout,
+// e.g. for the replicated workers. See NewQuorum.
+type Quorum struct {
+	h       *Heartbeat
+	k       int
+	created time.Time
+
+	mu      sync.Mutex
+	sources map[string]*quorumSource
+	lasts   []int64 // scratch space of update
+}
+
+// quorumSource is a source of a Quorum.
+type quorumSource struct {
+	q    *Quorum
+	name string
+	last atomic.Int64 // UnixNano of the last beat, zero until the first one
+}
+
+// QuorumError is wrapped by the cancellation cause of a Quorum, it lists the stale sources.
+type QuorumError struct {
+	// K is the number of fresh sources required.
+	K int
+	// Stale are the sources not beaten within the timeout, by name.
+	Stale []StaleSource
+}
+
+// StaleSource is a source not beaten within the timeout of a Quorum.
+type StaleSource struct {
+	Name string
+	// Idle is the time passed since the last beat of the source, or since the Quorum creation
+	// if it has never been beaten.
+	Idle time.Duration
+	// Beaten reports whether the source has ever been beaten.
+	Beaten bool
+}
+
+func (e *QuorumError) Error() string {
+	stale := make([]string, len(e.Stale))
+	for i, s := range e.Stale {
+		if s.Beaten {
+			stale[i] = fmt.Sprintf("%s (idle %s)", s.Name, s.Idle.Truncate(time.Millisecond))
+		} else {
+			stale[i] = fmt.Sprintf("%s (never beaten)", s.Name)
+		}
+	}
+	return fmt.Sprintf("heartbeat: quorum of %d lost, stale: %s", e.K, strings.Join(stale, ", "))
+}
+
+// NewQuorum creates a Quorum with the context cancelled once fewer than k sources have been beaten
+// within the timeout, with the cause wrapping a *QuorumError. Until k sources are beaten,
+// the timeout passes since the creation. opts are the options of the underlying Heartbeat.
+// Close must be called as usual.
+func NewQuorum(ctx context.Context, timeout time.Duration, k int, opts *Options) *Quorum {
+	if k <= 0 {
+		panic("positive k is required")
+	}
+
+	h := newHeartbeat(ctx, timeout, opts)
+	q := &Quorum{
+		h:       h,
+		k:       k,
+		created: h.created,
+		sources: make(map[string]*quorumSource),
+	}
+	h.explain = q.explain
+	h.start()
+
+	return q
+}
+
+// Source returns the Beater of the source with the given name, the same one for the same name.
+// A source registered late is stale until its first beat. Its context is the one of the Quorum.
+func (q *Quorum) Source(name string) Beater {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	s, ok := q.sources[name]
+	if !ok {
+		s = &quorumSource{q: q, name: name}
+		q.sources[name] = s
+	}
+	return s
+}
+
+// Ctx returns the context controlled by the Quorum.
+func (q *Quorum) Ctx() context.Context {
+	return q.h.Ctx()
+}
+
+// Heartbeat returns the underlying Heartbeat, beaten as of the k-th freshest source.
+func (q *Quorum) Heartbeat() *Heartbeat {
+	return q.h
+}
+
+// Close cancels the context controlled by the Quorum, see Heartbeat.Close.
+func (q *Quorum) Close() {
+	q.h.Close()
+}
+
+func (s *quorumSource) Beat() {
+	s.last.Store(time.Now().UnixNano())
+	s.q.update()
+}
+
+func (s *quorumSource) Ctx() context.Context {
+	return s.q.Ctx()
+}
+
+// update beats the Heartbeat as of the k-th freshest source, so that it expires once fewer than k are fresh.
+func (q *Quorum) update() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.sources) < q.k {
+		return
+	}
+
+	q.lasts = q.lasts[:0]
+	for _, s := range q.sources {
+		q.lasts = append(q.lasts, s.last.Load())
+	}
+	sort.Slice(q.lasts, func(i, j int) bool { return q.lasts[i] > q.lasts[j] })
+
+	if kth := q.lasts[q.k-1]; kth != 0 {
+		q.h.BeatAt(time.Unix(0, kth))
+	}
+}
+
+// explain lists the stale sources at the expiry.
+func (q *Quorum) explain(now time.Time) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	timeout := q.h.currentTimeout()
+	err := &QuorumError{K: q.k}
+	for _, s := range q.sources {
+		stale := StaleSource{Name: s.name, Idle: now.Sub(q.created)}
+		if last := s.last.Load(); last != 0 {
+			stale.Idle, stale.Beaten = now.Sub(time.Unix(0, last)), true
+		}
+		if stale.Idle >= timeout {
+			err.Stale = append(err.Stale, stale)
+		}
+	}
+	sort.Slice(err.Stale, func(i, j int) bool { return err.Stale[i].Name < err.Stale[j].Name })
+
+	return err
+}
@@ -0,0 +1,75 @@
+package heartbeat_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+func TestQuorum(t *testing.T) {
+	t.Parallel()
+
+	opts := &heartbeat.Options{CheckInterval: 10 * time.Millisecond}
+
+	t.Run("alive with k fresh sources", func(t *testing.T) {
+		t.Parallel()
+
+		q := heartbeat.NewQuorum(context.Background(), 100*time.Millisecond, 2, opts)
+		defer q.Close()
+
+		r1, r2, r3 := q.Source("replica-1"), q.Source("replica-2"), q.Source("replica-3")
+		require.Equal(t, r1, q.Source("replica-1"))
+		require.Equal(t, q.Ctx(), r1.Ctx())
+
+		// Any two of three keep the quorum.
+		for i := 0; i < 15; i++ {
+			if i < 10 {
+				r1.Beat()
+			}
+			r2.Beat()
+			if i >= 5 {
+				r3.Beat()
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		require.NoError(t, q.Ctx().Err())
+
+		// All stop beating, the late one never beats.
+		q.Source("replica-4")
+		<-q.Ctx().Done()
+
+		cause := context.Cause(q.Ctx())
+		require.ErrorIs(t, cause, heartbeat.ErrTimeout)
+		var qerr *heartbeat.QuorumError
+		require.ErrorAs(t, cause, &qerr)
+		require.Equal(t, 2, qerr.K)
+		require.Len(t, qerr.Stale, 4)
+		require.Equal(t, "replica-1", qerr.Stale[0].Name)
+		require.True(t, qerr.Stale[0].Beaten)
+		require.GreaterOrEqual(t, qerr.Stale[0].Idle, 100*time.Millisecond)
+		require.Equal(t, "replica-4", qerr.Stale[3].Name)
+		require.False(t, qerr.Stale[3].Beaten)
+		require.ErrorContains(t, cause, "heartbeat: quorum of 2 lost, stale: replica-1 (idle ")
+		require.ErrorContains(t, cause, "replica-4 (never beaten)")
+	})
+
+	t.Run("not enough sources", func(t *testing.T) {
+		t.Parallel()
+
+		q := heartbeat.NewQuorum(context.Background(), 50*time.Millisecond, 2, opts)
+		defer q.Close()
+
+		s := q.Source("only")
+		for i := 0; i < 5; i++ {
+			s.Beat()
+			time.Sleep(10 * time.Millisecond)
+		}
+		<-q.Ctx().Done()
+
+		var qerr *heartbeat.QuorumError
+		require.ErrorAs(t, context.Cause(q.Ctx()), &qerr)
+		require.Empty(t, qerr.Stale)
+	})
+}
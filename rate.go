@@ -0,0 +1,34 @@
+package heartbeat
+
+import "time"
+
+// RateHookFn is the signature of the rate hook, see Options.RateHook.
+type RateHookFn func(beatsPerSecond float64)
+
+// rateMeter measures the beat rate over a trailing window from the beat counts sampled by the checks.
+// It is owned by the checking goroutine.
+type rateMeter struct {
+	window  time.Duration
+	samples []rateSample // the oldest first, samples[0] is the base of the measurement
+}
+
+type rateSample struct {
+	at    time.Time
+	beats uint64
+}
+
+// observe records the beat count at now and returns the rate over the window.
+// The base is the latest sample at least the window old, so the rate covers the window or more.
+func (m *rateMeter) observe(now time.Time, beats uint64) float64 {
+	m.samples = append(m.samples, rateSample{at: now, beats: beats})
+	for len(m.samples) > 1 && !m.samples[1].at.After(now.Add(-m.window)) {
+		m.samples = m.samples[1:]
+	}
+
+	base := m.samples[0]
+	elapsed := now.Sub(base.at)
+	if elapsed <= 0 || beats < base.beats {
+		return 0
+	}
+	return float64(beats-base.beats) / elapsed.Seconds()
+}
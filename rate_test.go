@@ -0,0 +1,35 @@
+package heartbeat_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+func TestOptions_RateHook(t *testing.T) {
+	t.Parallel()
+
+	var rate atomic.Uint64
+	h := heartbeat.New(context.Background(), time.Second, &heartbeat.Options{
+		CheckInterval: 20 * time.Millisecond,
+		RateWindow:    200 * time.Millisecond,
+		RateHook: func(beatsPerSecond float64) {
+			rate.Store(uint64(beatsPerSecond))
+		},
+	})
+	defer h.Close()
+
+	// About 100 beats per second.
+	for i := 0; i < 50; i++ {
+		h.Beat()
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.InDelta(t, 100, rate.Load(), 40)
+
+	// The rate decays once the beats stop.
+	time.Sleep(300 * time.Millisecond)
+	require.Zero(t, rate.Load())
+}
@@ -0,0 +1,84 @@
+package heartbeat
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// Registry collects named Heartbeats, e.g. the Heartbeats of the stages of a pipeline, to tell which one stalled.
+// The Heartbeats stay registered once done, so that their state and cause can be inspected afterwards.
+type Registry struct {
+	mu sync.Mutex
+	hs map[string]*Heartbeat
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{hs: make(map[string]*Heartbeat)}
+}
+
+// Add registers h under name, replacing the Heartbeat registered under the same name, if any.
+func (r *Registry) Add(name string, h *Heartbeat) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hs[name] = h
+}
+
+// Remove unregisters the Heartbeat registered under name.
+func (r *Registry) Remove(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.hs, name)
+}
+
+// Get returns the Heartbeat registered under name.
+func (r *Registry) Get(name string) (*Heartbeat, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h, ok := r.hs[name]
+	return h, ok
+}
+
+// Names returns the sorted names of the registered Heartbeats.
+func (r *Registry) Names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.hs))
+	for name := range r.hs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Stats returns the Stats of the registered Heartbeats by name.
+func (r *Registry) Stats() map[string]Stats {
+	r.mu.Lock()
+	hs := make(map[string]*Heartbeat, len(r.hs))
+	for name, h := range r.hs {
+		hs[name] = h
+	}
+	r.mu.Unlock()
+
+	stats := make(map[string]Stats, len(hs))
+	for name, h := range hs {
+		stats[name] = h.Stats()
+	}
+	return stats
+}
+
+// registryKey is the key of the Registry stored in a context.
+type registryKey struct{}
+
+// WithRegistry returns a copy of ctx carrying r, the helpers like Stage register their Heartbeats in it.
+func WithRegistry(ctx context.Context, r *Registry) context.Context {
+	return context.WithValue(ctx, registryKey{}, r)
+}
+
+// registryFromContext returns the Registry stored in ctx by WithRegistry, if any.
+func registryFromContext(ctx context.Context) *Registry {
+	r, _ := ctx.Value(registryKey{}).(*Registry)
+	return r
+}
@@ -0,0 +1,37 @@
+package heartbeat_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	r := heartbeat.NewRegistry()
+	a := heartbeat.New(context.Background(), time.Minute, nil)
+	defer a.Close()
+	b := heartbeat.New(context.Background(), time.Minute, nil)
+	defer b.Close()
+
+	r.Add("b", b)
+	r.Add("a", a)
+	require.Equal(t, []string{"a", "b"}, r.Names())
+
+	h, ok := r.Get("a")
+	require.True(t, ok)
+	require.Same(t, a, h)
+
+	b.Beat()
+	stats := r.Stats()
+	require.Len(t, stats, 2)
+	require.Equal(t, uint64(1), stats["b"].Beats)
+
+	r.Remove("a")
+	_, ok = r.Get("a")
+	require.False(t, ok)
+	require.Equal(t, []string{"b"}, r.Names())
+}
@@ -0,0 +1,236 @@
+package heartbeat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// ErrAbandoned is matched by the error of Run when the operation didn't return within Options.DrainTimeout
+// after the Heartbeat expiry and was left running.
+var ErrAbandoned = errors.New("heartbeat: operation abandoned")
+
+// abandoned is the number of the abandoned operations still running.
+var abandoned atomic.Int64
+
+// Abandoned returns the number of the operations abandoned by Run that are still running.
+func Abandoned() int64 {
+	return abandoned.Load()
+}
+
+// RunFn is the signature of the operations run under a Heartbeat.
+// ctx is the context controlled by the Heartbeat, beat reports the progress.
+type RunFn func(ctx context.Context, beat func()) error
+
+// Run runs fn under a Heartbeat with the given timeout and guarantees the Heartbeat is closed afterwards.
+//
+// Once the Heartbeat expires, the returned error is the cancellation cause (a *TimeoutError),
+// even if fn swallowed the context error and returned nil.
+//
+// Run returns when fn returns. If Options.DrainTimeout is set, fn runs in its own goroutine and after the expiry
+// Run waits for it at most DrainTimeout. Then fn is abandoned: the error matches ErrAbandoned and the still running
+// fn is counted by Abandoned().
+func Run(ctx context.Context, timeout time.Duration, opts *Options, fn RunFn) error {
+	h := New(ctx, timeout, opts)
+	defer h.Close()
+
+	if opts == nil || opts.DrainTimeout <= 0 {
+		return h.result(fn(h.Ctx(), h.Beat))
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- fn(h.Ctx(), h.Beat)
+	}()
+
+	select {
+	case err := <-errc:
+		return h.result(err)
+	case <-h.Ctx().Done():
+	}
+
+	timer := time.NewTimer(opts.DrainTimeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-errc:
+		return h.result(err)
+	case <-timer.C:
+		abandoned.Add(1)
+		go func() {
+			<-errc
+			abandoned.Add(-1)
+		}()
+		return h.abandonedErr()
+	}
+}
+
+// RunHeartbeat is the same as Run without Options.DrainTimeout, but fn receives the Heartbeat itself,
+// e.g. to use SetPhase or Sleep.
+func RunHeartbeat(ctx context.Context, timeout time.Duration, opts *Options, fn func(h *Heartbeat) error) error {
+	h := New(ctx, timeout, opts)
+	defer h.Close()
+
+	return h.result(fn(h))
+}
+
+// Do runs fn under a Heartbeat with the given timeout and returns its result, see Run.
+// Unlike Run, Do doesn't wait for fn once the Heartbeat context is done, unless Options.DrainTimeout is set:
+// it returns the zero value and the error matching ErrAbandoned and wrapping the cause, e.g. a *TimeoutError,
+// right away. The abandoned fn is counted by Abandoned() until it returns and its result is discarded,
+// use DoRelease to release it.
+func Do[T any](ctx context.Context, timeout time.Duration, opts *Options,
+	fn func(ctx context.Context, beat func()) (T, error),
+) (T, error) {
+	return DoRelease(ctx, timeout, opts, fn, nil)
+}
+
+// DoRelease is the same as Do, but the result of the abandoned fn is passed to release once fn returns,
+// e.g. to close the resources it produced. release is called in the goroutine of fn.
+func DoRelease[T any](ctx context.Context, timeout time.Duration, opts *Options,
+	fn func(ctx context.Context, beat func()) (T, error), release func(v T, err error),
+) (T, error) {
+	h := New(ctx, timeout, opts)
+	defer h.Close()
+
+	type result struct {
+		v   T
+		err error
+	}
+	resc := make(chan result, 1)
+	go func() {
+		v, err := fn(h.Ctx(), h.Beat)
+		resc <- result{v: v, err: err}
+	}()
+
+	select {
+	case r := <-resc:
+		return r.v, h.result(r.err)
+	case <-h.Ctx().Done():
+	}
+
+	if opts != nil && opts.DrainTimeout > 0 {
+		timer := time.NewTimer(opts.DrainTimeout)
+		defer timer.Stop()
+
+		select {
+		case r := <-resc:
+			return r.v, h.result(r.err)
+		case <-timer.C:
+		}
+	}
+
+	abandoned.Add(1)
+	go func() {
+		r := <-resc
+		abandoned.Add(-1)
+		if release != nil {
+			release(r.v, r.err)
+		}
+	}()
+
+	var zero T
+	return zero, h.abandonedErr()
+}
+
+// abandonedErr returns the error of an abandoned operation, matching ErrAbandoned and wrapping the cause
+// of the Heartbeat context: the expiry or the cancellation of the parent context.
+func (h *heartbeat) abandonedErr() error {
+	err := h.result(nil)
+	if err == nil {
+		// The parent context is done.
+		err = context.Cause(h.hctx())
+	}
+	return fmt.Errorf("%w: %w", ErrAbandoned, err)
+}
+
+// result translates the error of an operation run under the Heartbeat: once the Heartbeat expired,
+// the cancellation cause is returned even if the operation swallowed the context error.
+func (h *heartbeat) result(err error) error {
+	if State(h.state.Load()) != StateExpired {
+		return err
+	}
+
+	cause := context.Cause(h.hctx())
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return cause
+	}
+	return fmt.Errorf("%w: %w", cause, err)
+}
+
+// RestartOptions defines optional parameters of RunWithRestart.
+type RestartOptions struct {
+	// Options are the options of the Heartbeat of every attempt.
+	Options *Options
+	// Backoff is the delay before the next attempt, doubled on every attempt up to MaxBackoff.
+	// There is no delay by default.
+	Backoff time.Duration
+	// MaxBackoff caps Backoff, DefaultMaxRestartBackoff by default.
+	MaxBackoff time.Duration
+}
+
+// AttemptsError is returned by RunWithRestart when the operation failed.
+type AttemptsError struct {
+	// Attempts is the number of the attempts made.
+	Attempts int
+	// Err is the error of the last attempt.
+	Err error
+}
+
+func (e *AttemptsError) Error() string {
+	return fmt.Sprintf("heartbeat: failed after %d attempts: %v", e.Attempts, e.Err)
+}
+
+func (e *AttemptsError) Unwrap() error {
+	return e.Err
+}
+
+// RunWithRestart runs fn under a fresh Heartbeat per attempt and restarts it when the Heartbeat expires,
+// up to the given number of attempts. fn is not restarted when it returns its own error or when ctx is done.
+// An attempt that expired counts as failed even if fn returned nil, its error is then the cancellation cause.
+//
+// The returned error is an *AttemptsError wrapping the error of the last attempt.
+func RunWithRestart(ctx context.Context, timeout time.Duration, opts *RestartOptions, attempts int, fn RunFn) error {
+	if attempts <= 0 {
+		panic("positive number of attempts is required")
+	}
+
+	var ropts RestartOptions
+	if opts != nil {
+		ropts = *opts
+	}
+	if ropts.MaxBackoff <= 0 {
+		ropts.MaxBackoff = DefaultMaxRestartBackoff
+	}
+
+	backoff := ropts.Backoff
+	for attempt := 1; ; attempt++ {
+		h := New(ctx, timeout, ropts.Options)
+		err := fn(h.Ctx(), h.Beat)
+		h.Close()
+
+		expired := h.State() == StateExpired && h.CancelReason() != ReasonParentDeadline && ctx.Err() == nil
+		err = h.result(err)
+		if err == nil {
+			return nil
+		}
+		if !expired || attempt == attempts {
+			return &AttemptsError{Attempts: attempt, Err: err}
+		}
+
+		if backoff > 0 {
+			select {
+			case <-ctx.Done():
+				return &AttemptsError{Attempts: attempt, Err: err}
+			case <-time.After(backoff):
+			}
+
+			backoff *= 2
+			if backoff > ropts.MaxBackoff {
+				backoff = ropts.MaxBackoff
+			}
+		}
+	}
+}
@@ -0,0 +1,353 @@
+package heartbeat_test
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+func TestRunWithRestart(t *testing.T) {
+	t.Parallel()
+
+	opts := &heartbeat.RestartOptions{
+		Options: &heartbeat.Options{CheckInterval: 10 * time.Millisecond},
+	}
+
+	t.Run("restarts until success", func(t *testing.T) {
+		t.Parallel()
+
+		attempts := 0
+		err := heartbeat.RunWithRestart(context.Background(), 50*time.Millisecond, opts, 3,
+			func(ctx context.Context, beat func()) error {
+				attempts++
+				if attempts < 3 {
+					<-ctx.Done()
+					return ctx.Err()
+				}
+				return nil
+			})
+
+		require.NoError(t, err)
+		require.Equal(t, 3, attempts)
+	})
+
+	t.Run("gives up", func(t *testing.T) {
+		t.Parallel()
+
+		attempts := 0
+		err := heartbeat.RunWithRestart(context.Background(), 50*time.Millisecond, opts, 2,
+			func(ctx context.Context, beat func()) error {
+				attempts++
+				<-ctx.Done()
+				return nil // expiry counts as failure anyway
+			})
+
+		var aerr *heartbeat.AttemptsError
+		require.ErrorAs(t, err, &aerr)
+		require.Equal(t, 2, aerr.Attempts)
+		require.ErrorIs(t, err, heartbeat.ErrTimeout)
+		require.Equal(t, 2, attempts)
+	})
+
+	t.Run("own error is not restarted", func(t *testing.T) {
+		t.Parallel()
+
+		errOwn := errors.New("own")
+		attempts := 0
+		err := heartbeat.RunWithRestart(context.Background(), time.Second, opts, 3,
+			func(ctx context.Context, beat func()) error {
+				attempts++
+				return errOwn
+			})
+
+		require.ErrorIs(t, err, errOwn)
+		require.Equal(t, 1, attempts)
+	})
+
+	t.Run("parent cancelled", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		attempts := 0
+		err := heartbeat.RunWithRestart(ctx, time.Second, opts, 3,
+			func(ctx context.Context, beat func()) error {
+				attempts++
+				<-ctx.Done()
+				return ctx.Err()
+			})
+
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Equal(t, 1, attempts)
+	})
+
+	t.Run("backoff", func(t *testing.T) {
+		t.Parallel()
+
+		var starts []time.Time
+		err := heartbeat.RunWithRestart(context.Background(), 50*time.Millisecond, &heartbeat.RestartOptions{
+			Options: opts.Options,
+			Backoff: 100 * time.Millisecond,
+		}, 3, func(ctx context.Context, beat func()) error {
+			starts = append(starts, time.Now())
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+		require.ErrorIs(t, err, heartbeat.ErrTimeout)
+		require.Len(t, starts, 3)
+		require.InDelta(t, 150*time.Millisecond, starts[1].Sub(starts[0]), float64(30*time.Millisecond))
+		require.InDelta(t, 250*time.Millisecond, starts[2].Sub(starts[1]), float64(30*time.Millisecond))
+	})
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+
+		err := heartbeat.Run(context.Background(), time.Second, nil, func(ctx context.Context, beat func()) error {
+			beat()
+			return nil
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("swallowed expiry", func(t *testing.T) {
+		t.Parallel()
+
+		var ctx context.Context
+		err := heartbeat.Run(context.Background(), 50*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+		}, func(hctx context.Context, beat func()) error {
+			ctx = hctx
+			<-hctx.Done()
+			return nil
+		})
+
+		var terr *heartbeat.TimeoutError
+		require.ErrorAs(t, err, &terr)
+		require.Equal(t, heartbeat.ReasonIdle, terr.Reason)
+		require.Error(t, ctx.Err())
+	})
+
+	t.Run("own error after expiry", func(t *testing.T) {
+		t.Parallel()
+
+		errOwn := errors.New("own")
+		err := heartbeat.Run(context.Background(), 50*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+		}, func(ctx context.Context, beat func()) error {
+			<-ctx.Done()
+			return errOwn
+		})
+
+		require.ErrorIs(t, err, errOwn)
+		require.ErrorIs(t, err, heartbeat.ErrTimeout)
+	})
+
+	t.Run("closed on return", func(t *testing.T) {
+		t.Parallel()
+
+		var ctx context.Context
+		err := heartbeat.Run(context.Background(), time.Minute, nil, func(hctx context.Context, beat func()) error {
+			ctx = hctx
+			return nil
+		})
+
+		require.NoError(t, err)
+		require.ErrorIs(t, ctx.Err(), context.Canceled)
+	})
+
+	t.Run("drain timeout", func(t *testing.T) {
+		t.Parallel()
+
+		release := make(chan struct{})
+		start := time.Now()
+		err := heartbeat.Run(context.Background(), 50*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+			DrainTimeout:  50 * time.Millisecond,
+		}, func(ctx context.Context, beat func()) error {
+			<-release // ignores ctx
+			return nil
+		})
+
+		require.ErrorIs(t, err, heartbeat.ErrAbandoned)
+		require.ErrorIs(t, err, heartbeat.ErrTimeout)
+		require.Less(t, time.Since(start), 500*time.Millisecond)
+		require.GreaterOrEqual(t, heartbeat.Abandoned(), int64(1))
+
+		close(release)
+		require.Eventually(t, func() bool { return heartbeat.Abandoned() == 0 }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("parent cancelled while draining", func(t *testing.T) {
+		t.Parallel()
+
+		errParent := errors.New("parent")
+		ctx, cancel := context.WithCancelCause(context.Background())
+		release := make(chan struct{})
+		err := heartbeat.Run(ctx, time.Minute, &heartbeat.Options{
+			DrainTimeout: 20 * time.Millisecond,
+		}, func(ctx context.Context, beat func()) error {
+			cancel(errParent)
+			<-release // ignores ctx
+			return nil
+		})
+		close(release)
+
+		require.ErrorIs(t, err, heartbeat.ErrAbandoned)
+		require.ErrorIs(t, err, errParent)
+		require.EqualError(t, err, "heartbeat: operation abandoned: parent")
+		require.Eventually(t, func() bool { return heartbeat.Abandoned() == 0 }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("drained", func(t *testing.T) {
+		t.Parallel()
+
+		err := heartbeat.Run(context.Background(), 50*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+			DrainTimeout:  time.Second,
+		}, func(ctx context.Context, beat func()) error {
+			<-ctx.Done()
+			time.Sleep(20 * time.Millisecond)
+			return ctx.Err()
+		})
+
+		require.ErrorIs(t, err, heartbeat.ErrTimeout)
+		require.NotErrorIs(t, err, heartbeat.ErrAbandoned)
+	})
+}
+
+func TestRunHeartbeat(t *testing.T) {
+	t.Parallel()
+
+	opts := &heartbeat.Options{CheckInterval: 10 * time.Millisecond}
+
+	t.Run("completes", func(t *testing.T) {
+		t.Parallel()
+
+		var h *heartbeat.Heartbeat
+		err := heartbeat.RunHeartbeat(context.Background(), 50*time.Millisecond, opts, func(hb *heartbeat.Heartbeat) error {
+			h = hb
+			for i := 0; i < 5; i++ {
+				hb.SetPhase("step")
+				if err := hb.Sleep(20 * time.Millisecond); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+		require.NoError(t, err)
+		require.Equal(t, heartbeat.StateClosed, h.State())
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		t.Parallel()
+
+		err := heartbeat.RunHeartbeat(context.Background(), 50*time.Millisecond, opts, func(h *heartbeat.Heartbeat) error {
+			<-h.Ctx().Done()
+			return nil
+		})
+
+		require.ErrorIs(t, err, heartbeat.ErrTimeout)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+
+		errFailed := errors.New("failed")
+		err := heartbeat.RunHeartbeat(context.Background(), time.Minute, opts, func(*heartbeat.Heartbeat) error {
+			return errFailed
+		})
+
+		require.ErrorIs(t, err, errFailed)
+	})
+}
+
+func TestDo(t *testing.T) {
+	t.Parallel()
+
+	opts := &heartbeat.Options{CheckInterval: 10 * time.Millisecond}
+
+	t.Run("completes", func(t *testing.T) {
+		t.Parallel()
+
+		v, err := heartbeat.Do(context.Background(), 50*time.Millisecond, opts, func(ctx context.Context, beat func()) (int, error) {
+			for i := 0; i < 5; i++ {
+				time.Sleep(20 * time.Millisecond)
+				beat()
+			}
+			return 42, nil
+		})
+
+		require.NoError(t, err)
+		require.Equal(t, 42, v)
+	})
+
+	t.Run("abandoned", func(t *testing.T) {
+		t.Parallel()
+
+		unblock := make(chan struct{})
+		released := make(chan int, 1)
+		start := time.Now()
+		v, err := heartbeat.DoRelease(context.Background(), 50*time.Millisecond, opts,
+			func(ctx context.Context, beat func()) (int, error) {
+				<-unblock // ignores ctx
+				return 42, nil
+			}, func(v int, _ error) {
+				released <- v
+			})
+
+		require.Zero(t, v)
+		require.ErrorIs(t, err, heartbeat.ErrAbandoned)
+		var terr *heartbeat.TimeoutError
+		require.ErrorAs(t, err, &terr)
+		require.Equal(t, heartbeat.ReasonIdle, terr.Reason)
+		require.EqualError(t, err, "heartbeat: operation abandoned: "+terr.Error())
+		require.Less(t, time.Since(start), 200*time.Millisecond)
+		require.GreaterOrEqual(t, heartbeat.Abandoned(), int64(1))
+
+		close(unblock)
+		require.Equal(t, 42, <-released)
+		require.Eventually(t, func() bool { return heartbeat.Abandoned() == 0 }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("parent cancelled", func(t *testing.T) {
+		t.Parallel()
+
+		errParent := errors.New("parent")
+		ctx, cancel := context.WithCancelCause(context.Background())
+		cancel(errParent)
+		_, err := heartbeat.Do(ctx, time.Minute, opts, func(ctx context.Context, beat func()) (string, error) {
+			time.Sleep(20 * time.Millisecond)
+			return "", nil
+		})
+
+		require.ErrorIs(t, err, heartbeat.ErrAbandoned)
+		require.ErrorIs(t, err, errParent)
+		require.EqualError(t, err, "heartbeat: operation abandoned: parent")
+	})
+
+	t.Run("drained", func(t *testing.T) {
+		t.Parallel()
+
+		v, err := heartbeat.Do(context.Background(), 50*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+			DrainTimeout:  time.Second,
+		}, func(ctx context.Context, beat func()) (int, error) {
+			<-ctx.Done()
+			return 1, ctx.Err()
+		})
+
+		require.Equal(t, 1, v)
+		require.ErrorIs(t, err, heartbeat.ErrTimeout)
+		require.NotErrorIs(t, err, heartbeat.ErrAbandoned)
+	})
+}
@@ -0,0 +1,52 @@
+package heartbeat
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// timeoutScale is the bits of the float64 set by SetTimeoutScale, zero stands for 1.
+var timeoutScale atomic.Uint64
+
+// SetTimeoutScale sets the factor multiplying the timeouts of the Heartbeats created afterwards, unless
+// overridden by Options.TimeoutScale, e.g. to stretch them under the race detector or on a slow CI machine.
+// The factor is captured by New, changing it doesn't affect the existing Heartbeats.
+func SetTimeoutScale(f float64) {
+	checkScale(f)
+	timeoutScale.Store(math.Float64bits(f))
+}
+
+func checkScale(f float64) {
+	if !validScale(f) {
+		panic("positive timeout scale is required")
+	}
+}
+
+func validScale(f float64) bool {
+	return f > 0 && !math.IsInf(f, 1)
+}
+
+// TimeoutScale returns the factor set by SetTimeoutScale, 1 by default.
+func TimeoutScale() float64 {
+	if bits := timeoutScale.Load(); bits != 0 {
+		return math.Float64frombits(bits)
+	}
+	return 1
+}
+
+// scaled returns d multiplied by the timeout scale of the Heartbeat.
+func (h *heartbeat) scaled(d time.Duration) time.Duration {
+	if h.scale == 1 {
+		return d
+	}
+	return time.Duration(float64(d) * h.scale)
+}
+
+// unscaled returns d divided by the timeout scale of the Heartbeat, e.g. to persist the configured timeout.
+func (h *heartbeat) unscaled(d time.Duration) time.Duration {
+	if h.scale == 1 {
+		return d
+	}
+	return time.Duration(math.Round(float64(d) / h.scale))
+}
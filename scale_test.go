@@ -0,0 +1,72 @@
+package heartbeat_test
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+// Not parallel, the scale is global.
+func TestSetTimeoutScale(t *testing.T) {
+	require.Equal(t, 1.0, heartbeat.TimeoutScale())
+	require.Panics(t, func() { heartbeat.SetTimeoutScale(0) })
+	require.Panics(t, func() { heartbeat.SetTimeoutScale(-1) })
+
+	heartbeat.SetTimeoutScale(3)
+	defer heartbeat.SetTimeoutScale(1)
+	require.Equal(t, 3.0, heartbeat.TimeoutScale())
+
+	h := heartbeat.New(context.Background(), time.Second, nil)
+	defer h.Close()
+	unscaled := heartbeat.New(context.Background(), time.Second, &heartbeat.Options{TimeoutScale: 1})
+	defer unscaled.Close()
+
+	// The scale is captured at creation.
+	heartbeat.SetTimeoutScale(1)
+	require.Equal(t, 3*time.Second, h.Stats().Timeout)
+	require.Equal(t, 3.0, h.Stats().TimeoutScale)
+	require.Equal(t, time.Second, unscaled.Stats().Timeout)
+}
+
+func TestOptions_TimeoutScale(t *testing.T) {
+	t.Parallel()
+
+	require.Panics(t, func() {
+		heartbeat.New(context.Background(), time.Second, &heartbeat.Options{TimeoutScale: -1})
+	})
+
+	checked := make(chan time.Duration, 1)
+	h := heartbeat.New(context.Background(), 50*time.Millisecond, &heartbeat.Options{
+		CheckInterval: 10 * time.Millisecond,
+		TimeoutScale:  2,
+		CheckHook: func(timeout, _, _ time.Duration) {
+			select {
+			case checked <- timeout:
+			default:
+			}
+		},
+	})
+	defer h.Close()
+	require.Equal(t, 100*time.Millisecond, <-checked)
+
+	h.SetTimeout(time.Minute)
+	require.Equal(t, 2*time.Minute, h.Stats().Timeout)
+
+	// The lifetime is scaled too.
+	lifetime := heartbeat.New(context.Background(), time.Minute, &heartbeat.Options{
+		CheckInterval: 10 * time.Millisecond,
+		MaxLifetime:   50 * time.Millisecond,
+		TimeoutScale:  2,
+	})
+	defer lifetime.Close()
+	start := time.Now()
+	<-lifetime.Ctx().Done()
+	require.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+
+	var timeoutErr *heartbeat.TimeoutError
+	require.True(t, errors.As(context.Cause(lifetime.Ctx()), &timeoutErr))
+	require.Equal(t, heartbeat.ReasonMaxLifetime, timeoutErr.Reason)
+}
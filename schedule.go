@@ -0,0 +1,116 @@
+package heartbeat
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Schedule makes the Heartbeat expect a beat within the tolerance after every scheduled slot,
+// e.g. for the producers reporting every 5 minutes on the minute, see Options.Schedule.
+// The slots are at Offset plus the multiples of Interval since the Unix epoch, in the wall clock.
+type Schedule struct {
+	// Interval is the period of the slots.
+	Interval time.Duration
+	// Offset shifts the slots from the multiples of Interval, e.g. 30s for every minute at :30.
+	Offset time.Duration
+	// Tolerance is how long after a slot the beat may arrive.
+	Tolerance time.Duration
+	// MaxMissed is the number of the consecutive missed slots expiring the Heartbeat, 1 by default.
+	MaxMissed int
+}
+
+// schedule tracks the slots of a Schedule. The slots are numbered since the Unix epoch.
+type schedule struct {
+	Schedule
+
+	hit    atomic.Int64 // the latest slot beaten within the tolerance
+	next   atomic.Int64 // the earliest slot not evaluated yet
+	missed int          // the consecutive missed slots, owned by the checking goroutine
+	last   atomic.Int64 // the latest missed slot
+}
+
+func newSchedule(s Schedule, now time.Time) *schedule {
+	if s.Interval <= 0 {
+		panic("positive schedule interval is required")
+	}
+	if s.MaxMissed <= 0 {
+		s.MaxMissed = 1
+	}
+
+	sc := &schedule{Schedule: s}
+	sc.hit.Store(-1)
+	sc.restart(now)
+	return sc
+}
+
+// restart skips the slots until now, the first slot evaluated is the one after now.
+func (s *schedule) restart(now time.Time) {
+	s.next.Store(s.slot(now) + 1)
+	s.missed = 0
+}
+
+// slot returns the latest slot at or before t.
+func (s *schedule) slot(t time.Time) int64 {
+	d := t.UnixNano() - int64(s.Offset)
+	k := d / int64(s.Interval)
+	if d < 0 && d%int64(s.Interval) != 0 {
+		k--
+	}
+	return k
+}
+
+// at returns the time of the slot k.
+func (s *schedule) at(k int64) time.Time {
+	return time.Unix(0, k*int64(s.Interval)+int64(s.Offset))
+}
+
+// beat records the beat at t for its slot if it arrived within the tolerance.
+func (s *schedule) beat(t time.Time) {
+	k := s.slot(t)
+	if t.Sub(s.at(k)) > s.Tolerance {
+		return
+	}
+	for {
+		hit := s.hit.Load()
+		if hit >= k || s.hit.CompareAndSwap(hit, k) {
+			return
+		}
+	}
+}
+
+// check evaluates the slots whose tolerance has passed by now and reports whether too many were missed.
+// A slot counts as beaten if it or a later one was, in case the checks fell behind.
+func (s *schedule) check(now time.Time) bool {
+	for k := s.next.Load(); !now.Before(s.at(k).Add(s.Tolerance)); k++ {
+		if s.hit.Load() >= k {
+			s.missed = 0
+		} else {
+			s.missed++
+			s.last.Store(k)
+		}
+		s.next.Store(k + 1)
+
+		if s.missed >= s.MaxMissed {
+			return true
+		}
+	}
+	return false
+}
+
+// expected returns the time of the next slot expecting a beat.
+func (s *schedule) expected() time.Time {
+	k := s.next.Load()
+	if hit := s.hit.Load(); hit >= k {
+		k = hit + 1
+	}
+	return s.at(k)
+}
+
+// NextSlot returns the time of the next slot expecting a beat, or zero time without Options.Schedule.
+// The slot is expected to be beaten within Schedule.Tolerance after the time.
+func (h *Heartbeat) NextSlot() time.Time {
+	if h.schedule == nil {
+		return time.Time{}
+	}
+	return h.schedule.expected()
+}
@@ -0,0 +1,107 @@
+package heartbeat_test
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+func TestOptions_Schedule(t *testing.T) {
+	t.Parallel()
+
+	schedule := &heartbeat.Schedule{
+		Interval:  100 * time.Millisecond,
+		Offset:    20 * time.Millisecond,
+		Tolerance: 30 * time.Millisecond,
+	}
+	h := heartbeat.New(context.Background(), time.Minute, &heartbeat.Options{
+		CheckInterval: 5 * time.Millisecond,
+		Schedule:      schedule,
+	})
+	defer h.Close()
+
+	for i := 0; i < 5; i++ {
+		slot := h.NextSlot()
+		require.Equal(t, 20*time.Millisecond, time.Duration(slot.UnixNano())%schedule.Interval)
+		time.Sleep(time.Until(slot) + 10*time.Millisecond)
+		h.Beat()
+		require.Equal(t, slot.Add(schedule.Interval), h.NextSlot())
+	}
+	require.NoError(t, h.Ctx().Err())
+
+	missed := h.NextSlot()
+	require.Eventually(t, func() bool {
+		return h.State() == heartbeat.StateExpired
+	}, time.Second, 5*time.Millisecond)
+	require.Equal(t, heartbeat.ReasonMissedSlot, h.CancelReason())
+	require.WithinDuration(t, missed.Add(schedule.Tolerance), time.Now(), 30*time.Millisecond)
+
+	var timeoutErr *heartbeat.TimeoutError
+	require.True(t, errors.As(context.Cause(h.Ctx()), &timeoutErr))
+	require.True(t, missed.Equal(timeoutErr.Slot))
+	require.ErrorContains(t, timeoutErr, "missed slot at "+missed.Format(time.RFC3339Nano))
+}
+
+func TestOptions_Schedule_maxMissed(t *testing.T) {
+	t.Parallel()
+
+	h := heartbeat.New(context.Background(), time.Minute, &heartbeat.Options{
+		CheckInterval: 5 * time.Millisecond,
+		Schedule: &heartbeat.Schedule{
+			Interval:  50 * time.Millisecond,
+			Tolerance: 20 * time.Millisecond,
+			MaxMissed: 3,
+		},
+	})
+	defer h.Close()
+
+	// A late beat misses its slot, the next one within the tolerance resets the count.
+	late := h.NextSlot()
+	time.Sleep(time.Until(late) + 30*time.Millisecond)
+	h.Beat()
+	time.Sleep(time.Until(late.Add(50*time.Millisecond)) + 5*time.Millisecond)
+	h.Beat()
+	require.NoError(t, h.Ctx().Err())
+
+	start := time.Now()
+	<-h.Ctx().Done()
+	require.Equal(t, heartbeat.ReasonMissedSlot, h.CancelReason())
+	require.InDelta(t, 3*50*time.Millisecond, time.Since(start), float64(50*time.Millisecond))
+}
+
+func TestOptions_Schedule_dontCancel(t *testing.T) {
+	t.Parallel()
+
+	warned := make(chan time.Time, 10)
+	h := heartbeat.New(context.Background(), time.Minute, &heartbeat.Options{
+		CheckInterval: 5 * time.Millisecond,
+		Schedule: &heartbeat.Schedule{
+			Interval:  50 * time.Millisecond,
+			Tolerance: 10 * time.Millisecond,
+		},
+		DontCancel: true,
+		CancelHook: func(_, _, _ time.Duration) {
+			warned <- time.Now()
+		},
+	})
+	defer h.Close()
+
+	// Every missed slot is reported, the context is not cancelled.
+	missed := h.NextSlot()
+	for i := 0; i < 2; i++ {
+		var at time.Time
+		select {
+		case at = <-warned:
+		case <-h.Ctx().Done():
+			t.Fatal("the missed slot cancelled the context")
+		}
+		require.WithinDuration(t, missed.Add(10*time.Millisecond), at, 30*time.Millisecond)
+		missed = missed.Add(50 * time.Millisecond)
+	}
+	require.NoError(t, h.Ctx().Err())
+	require.Equal(t, heartbeat.StateRunning, h.State())
+	require.Equal(t, heartbeat.ReasonNone, h.CancelReason())
+}
@@ -0,0 +1,49 @@
+package heartbeat
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// BeatOnSignal beats h every time one of the signals is delivered to the process.
+// If no signals are given, SIGUSR1 is used on Unix systems.
+//
+// Every call installs its own relay, so several Beaters can listen to the same signal.
+// The relay is removed when stop is called or when the context of h is done.
+func BeatOnSignal(h Beater, sig ...os.Signal) (stop func()) {
+	if len(sig) == 0 {
+		sig = defaultBeatSignals
+	}
+	if len(sig) == 0 {
+		panic("signal is required")
+	}
+
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, sig...)
+
+	done := make(chan struct{})
+	exited := make(chan struct{})
+
+	go func() {
+		defer close(exited)
+		defer signal.Stop(ch)
+
+		for {
+			select {
+			case <-h.Ctx().Done():
+				return
+			case <-done:
+				return
+			case <-ch:
+				h.Beat()
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		<-exited
+	}
+}
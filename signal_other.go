@@ -0,0 +1,7 @@
+//go:build !unix
+
+package heartbeat
+
+import "os"
+
+var defaultBeatSignals []os.Signal
@@ -0,0 +1,47 @@
+//go:build unix
+
+package heartbeat_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+func TestBeatOnSignal(t *testing.T) {
+	newHeartbeat := func() *heartbeat.Heartbeat {
+		return heartbeat.New(context.Background(), time.Second, &heartbeat.Options{
+			CheckInterval: 50 * time.Millisecond,
+			DontCancel:    true,
+		})
+	}
+
+	h1 := newHeartbeat()
+	defer h1.Close()
+	h2 := newHeartbeat()
+	defer h2.Close()
+
+	stop1 := heartbeat.BeatOnSignal(h1)
+	defer stop1()
+	stop2 := heartbeat.BeatOnSignal(h2, syscall.SIGUSR1)
+	defer stop2()
+
+	time.Sleep(300 * time.Millisecond)
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+	time.Sleep(50 * time.Millisecond)
+
+	require.Greater(t, h1.Remaining(), 800*time.Millisecond)
+	require.Greater(t, h2.Remaining(), 800*time.Millisecond)
+
+	stop1()
+	time.Sleep(300 * time.Millisecond)
+	require.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+	time.Sleep(50 * time.Millisecond)
+
+	require.Less(t, h1.Remaining(), 800*time.Millisecond)
+	require.Greater(t, h2.Remaining(), 800*time.Millisecond)
+}
@@ -0,0 +1,10 @@
+//go:build unix
+
+package heartbeat
+
+import (
+	"os"
+	"syscall"
+)
+
+var defaultBeatSignals = []os.Signal{syscall.SIGUSR1}
@@ -0,0 +1,59 @@
+package heartbeat
+
+import (
+	"context"
+	"time"
+)
+
+// Sleep pauses for d, unless the Heartbeat context is done first. It returns nil after a full pause,
+// or the cancellation cause otherwise, e.g. a *TimeoutError once the Heartbeat expires.
+func (h *Heartbeat) Sleep(d time.Duration) error {
+	ctx := h.Ctx()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	}
+}
+
+// Tick returns a channel delivering the ticks of a time.Ticker with period d while the Heartbeat context is alive.
+// The channel is closed once the context is done, so it can be ranged over. Like with time.Ticker,
+// the ticks are dropped for slow receivers. If beat is set, every delivered tick beats the Heartbeat.
+func (h *Heartbeat) Tick(d time.Duration, beat bool) <-chan time.Time {
+	if d <= 0 {
+		panic("positive period is required")
+	}
+
+	ctx := h.Ctx()
+	c := make(chan time.Time)
+
+	go func() {
+		defer close(c)
+
+		ticker := time.NewTicker(d)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				select {
+				case <-ctx.Done():
+					return
+				case c <- now:
+					if beat {
+						h.Beat()
+					}
+				}
+			}
+		}
+	}()
+
+	return c
+}
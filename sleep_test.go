@@ -0,0 +1,86 @@
+package heartbeat_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+func TestHeartbeat_Sleep(t *testing.T) {
+	t.Parallel()
+
+	t.Run("full pause", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), time.Second, nil)
+		defer h.Close()
+
+		start := time.Now()
+		require.NoError(t, h.Sleep(50*time.Millisecond))
+		require.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	})
+
+	t.Run("returns early on expiry", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), 50*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+		})
+		defer h.Close()
+
+		start := time.Now()
+		require.ErrorIs(t, h.Sleep(time.Minute), heartbeat.ErrTimeout)
+		require.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("returns early on close", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), time.Minute, nil)
+		time.AfterFunc(50*time.Millisecond, h.Close)
+
+		require.ErrorIs(t, h.Sleep(time.Minute), context.Canceled)
+	})
+}
+
+func TestHeartbeat_Tick(t *testing.T) {
+	t.Parallel()
+
+	t.Run("beats until closed", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), 50*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+		})
+		defer h.Close()
+
+		var ticks int
+		for range h.Tick(20*time.Millisecond, true) {
+			if ticks++; ticks == 10 {
+				h.Close()
+			}
+		}
+		require.Equal(t, 10, ticks)
+		require.Equal(t, heartbeat.StateClosed, h.State())
+		// The last tick may beat after Close.
+		require.GreaterOrEqual(t, h.Stats().Beats, uint64(9))
+	})
+
+	t.Run("stops on expiry", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), 50*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+		})
+		defer h.Close()
+
+		var ticks int
+		for range h.Tick(20*time.Millisecond, false) {
+			ticks++
+		}
+		require.ErrorIs(t, context.Cause(h.Ctx()), heartbeat.ErrTimeout)
+		require.Less(t, ticks, 5)
+	})
+}
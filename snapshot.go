@@ -0,0 +1,58 @@
+package heartbeat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// snapshot is the persisted state of a Heartbeat, see Snapshot.
+type snapshot struct {
+	Name     string    `json:"name,omitempty"`
+	Timeout  string    `json:"timeout"`
+	LastBeat time.Time `json:"last_beat"`
+	Beats    uint64    `json:"beats"`
+}
+
+// Snapshot serializes the name, the timeout, the wall-clock time of the last beat and the number of beats
+// of the Heartbeat, so that Restore can resume the countdown after a process restart.
+// The timeout is persisted before the scaling, see SetTimeoutScale: Restore applies the scale in effect then.
+func (h *Heartbeat) Snapshot() ([]byte, error) {
+	return json.Marshal(snapshot{
+		Name:     h.name,
+		Timeout:  h.unscaled(time.Duration(h.timeout.Load())).String(),
+		LastBeat: h.lastBeatTime(),
+		Beats:    h.beats.Load(),
+	})
+}
+
+// Restore creates and starts a Heartbeat from the Snapshot data, measuring the idle time since the persisted
+// last beat: a Heartbeat overdue before the restart expires on the first check. The last beat in the future,
+// e.g. because of a clock skew between the hosts, is clamped to now.
+// The name and the timeout come from the snapshot, the other options from opts.
+func Restore(ctx context.Context, data []byte, opts *Options) (*Heartbeat, error) {
+	var s snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("heartbeat: restore: %w", err)
+	}
+	timeout, err := time.ParseDuration(s.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("heartbeat: restore: %w", err)
+	}
+	if timeout <= 0 {
+		return nil, fmt.Errorf("heartbeat: restore: invalid timeout %s", s.Timeout)
+	}
+
+	config := opts.Merge(&Options{Name: s.Name})
+	h := newHeartbeat(ctx, timeout, config)
+
+	if last := s.LastBeat.Sub(h.created); last < 0 {
+		h.lastBeat.Store(int64(last))
+	}
+	h.beats.Store(s.Beats)
+	h.beaten.Store(s.Beats > 0)
+	h.start()
+
+	return h, nil
+}
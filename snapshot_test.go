@@ -0,0 +1,113 @@
+package heartbeat_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+func TestHeartbeat_Snapshot(t *testing.T) {
+	t.Parallel()
+
+	opts := &heartbeat.Options{CheckInterval: 10 * time.Millisecond}
+
+	t.Run("resumes the countdown", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), 200*time.Millisecond, &heartbeat.Options{Name: "job-42"})
+		h.Beat()
+		h.Beat()
+		time.Sleep(100 * time.Millisecond)
+		data, err := h.Snapshot()
+		require.NoError(t, err)
+		h.Close()
+
+		restored, err := heartbeat.Restore(context.Background(), data, opts)
+		require.NoError(t, err)
+		defer restored.Close()
+
+		require.Equal(t, "job-42", restored.Name())
+		stats := restored.Stats()
+		require.Equal(t, 200*time.Millisecond, stats.Timeout)
+		require.Equal(t, uint64(2), stats.Beats)
+		require.GreaterOrEqual(t, stats.Idle, 100*time.Millisecond)
+
+		start := time.Now()
+		<-restored.Ctx().Done()
+		require.ErrorIs(t, context.Cause(restored.Ctx()), heartbeat.ErrTimeout)
+		require.Less(t, time.Since(start), 150*time.Millisecond)
+	})
+
+	t.Run("overdue", func(t *testing.T) {
+		t.Parallel()
+
+		data, err := json.Marshal(map[string]any{
+			"name":      "job-42",
+			"timeout":   "1m0s",
+			"last_beat": time.Now().Add(-time.Hour),
+			"beats":     7,
+		})
+		require.NoError(t, err)
+
+		h, err := heartbeat.Restore(context.Background(), data, opts)
+		require.NoError(t, err)
+		defer h.Close()
+
+		<-h.Ctx().Done()
+		require.ErrorIs(t, context.Cause(h.Ctx()), heartbeat.ErrTimeout)
+	})
+
+	t.Run("future last beat is clamped", func(t *testing.T) {
+		t.Parallel()
+
+		data, err := json.Marshal(map[string]any{
+			"timeout":   "1m0s",
+			"last_beat": time.Now().Add(time.Hour),
+		})
+		require.NoError(t, err)
+
+		h, err := heartbeat.Restore(context.Background(), data, opts)
+		require.NoError(t, err)
+		defer h.Close()
+
+		require.WithinDuration(t, time.Now(), h.Stats().LastBeat, 10*time.Millisecond)
+		require.InDelta(t, time.Minute, h.Remaining(), float64(10*time.Millisecond))
+	})
+
+	t.Run("scaled timeout", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), time.Minute, &heartbeat.Options{TimeoutScale: 3})
+		require.Equal(t, 3*time.Minute, h.Stats().Timeout)
+		data, err := h.Snapshot()
+		require.NoError(t, err)
+		h.Close()
+
+		var fields map[string]any
+		require.NoError(t, json.Unmarshal(data, &fields))
+		require.Equal(t, "1m0s", fields["timeout"])
+
+		restored, err := heartbeat.Restore(context.Background(), data, &heartbeat.Options{TimeoutScale: 3})
+		require.NoError(t, err)
+		defer restored.Close()
+		require.Equal(t, 3*time.Minute, restored.Stats().Timeout)
+
+		unscaled, err := heartbeat.Restore(context.Background(), data, nil)
+		require.NoError(t, err)
+		defer unscaled.Close()
+		require.Equal(t, time.Minute, unscaled.Stats().Timeout)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := heartbeat.Restore(context.Background(), []byte(`{"timeout":"0s"}`), nil)
+		require.EqualError(t, err, "heartbeat: restore: invalid timeout 0s")
+
+		_, err = heartbeat.Restore(context.Background(), []byte(`{`), nil)
+		require.ErrorContains(t, err, "heartbeat: restore: ")
+	})
+}
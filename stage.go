@@ -0,0 +1,113 @@
+package heartbeat
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// StageError is the cause of the cancellation of a Stage when its function fails.
+type StageError struct {
+	// Stage is the name of the stage.
+	Stage string
+	// Err is the error returned by the function.
+	Err error
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("heartbeat: stage %q: %v", e.Stage, e.Err)
+}
+
+// Unwrap returns Err.
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// StageFn is a stage of a channel pipeline: it processes the items received from the input channel
+// and sends the results to the returned channel, closed once the stage is done.
+type StageFn[In, Out any] func(in <-chan In) <-chan Out
+
+// Stage returns a pipeline stage calling fn for every item under a Heartbeat of its own, named after the stage,
+// so that a stall tells which stage is wedged. The stage beats per processed item, the Heartbeat is paused
+// while the stage waits for an item or for the next stage to take the result, so only fn is timed.
+// fn can beat on its own via ctx, see Beat.
+//
+// The expiry cancels the context of this stage only, the cause is a *TimeoutError naming the stage.
+// An error of fn stops the stage with a *StageError cause. Either way the output channel is closed
+// and the remaining input is discarded, so that the upstream stages don't block, until the input channel
+// is closed or ctx is done: the producers feeding the input must close it or stop sending once ctx is done.
+// The Heartbeat is registered in the Registry carried by ctx, if any, see WithRegistry.
+func Stage[In, Out any](ctx context.Context, name string, timeout time.Duration, opts *Options,
+	fn func(ctx context.Context, v In) (Out, error),
+) StageFn[In, Out] {
+	return func(in <-chan In) <-chan Out {
+		var o Options
+		if opts != nil {
+			o = *opts
+		}
+		o.Name = name
+		h := New(ctx, timeout, &o)
+		h.Pause()
+		if r := registryFromContext(ctx); r != nil {
+			r.Add(name, h)
+		}
+
+		out := make(chan Out)
+		go func() {
+			defer close(out)
+			defer h.Close()
+			defer func() {
+				go func() {
+					for {
+						select {
+						case <-ctx.Done():
+							return
+						case _, ok := <-in:
+							if !ok {
+								return
+							}
+						}
+					}
+				}()
+			}()
+
+			hctx := h.Ctx()
+			for {
+				var v In
+				select {
+				case <-hctx.Done():
+					return
+				case item, ok := <-in:
+					if !ok {
+						return
+					}
+					v = item
+				}
+
+				h.Resume()
+				res, err := fn(hctx, v)
+				if err != nil {
+					h.close(&StageError{Stage: name, Err: err})
+					return
+				}
+				h.Beat()
+				h.Pause()
+
+				select {
+				case <-hctx.Done():
+					return
+				case out <- res:
+				}
+			}
+		}()
+
+		return out
+	}
+}
+
+// Then joins the stages, the output of first feeds second.
+func Then[A, B, C any](first StageFn[A, B], second StageFn[B, C]) StageFn[A, C] {
+	return func(in <-chan A) <-chan C {
+		return second(first(in))
+	}
+}
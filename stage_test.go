@@ -0,0 +1,141 @@
+package heartbeat_test
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+func TestStage(t *testing.T) {
+	t.Parallel()
+
+	opts := &heartbeat.Options{CheckInterval: 10 * time.Millisecond}
+	gen := func(n int) <-chan int {
+		c := make(chan int)
+		go func() {
+			defer close(c)
+			for i := 0; i < n; i++ {
+				c <- i
+			}
+		}()
+		return c
+	}
+
+	t.Run("pipeline", func(t *testing.T) {
+		t.Parallel()
+
+		r := heartbeat.NewRegistry()
+		ctx := heartbeat.WithRegistry(context.Background(), r)
+		double := heartbeat.Stage(ctx, "double", 50*time.Millisecond, opts, func(_ context.Context, v int) (int, error) {
+			return 2 * v, nil
+		})
+		format := heartbeat.Stage(ctx, "format", 50*time.Millisecond, opts, func(_ context.Context, v int) (string, error) {
+			return strconv.Itoa(v), nil
+		})
+
+		var got []string
+		for s := range heartbeat.Then(double, format)(gen(3)) {
+			got = append(got, s)
+			// The stages wait for the sink, it doesn't count as their idle time.
+			time.Sleep(70 * time.Millisecond)
+		}
+
+		require.Equal(t, []string{"0", "2", "4"}, got)
+		require.Equal(t, []string{"double", "format"}, r.Names())
+		for name, stats := range r.Stats() {
+			require.Equal(t, heartbeat.StateClosed, stats.State, name)
+			require.Equal(t, uint64(3), stats.Beats, name)
+		}
+	})
+
+	t.Run("wedged", func(t *testing.T) {
+		t.Parallel()
+
+		r := heartbeat.NewRegistry()
+		ctx := heartbeat.WithRegistry(context.Background(), r)
+		wedged := heartbeat.Stage(ctx, "wedged", 50*time.Millisecond, opts, func(ctx context.Context, v int) (int, error) {
+			if v == 1 {
+				<-ctx.Done()
+				return 0, ctx.Err()
+			}
+			return v, nil
+		})
+		sink := heartbeat.Stage(ctx, "sink", 50*time.Millisecond, opts, func(_ context.Context, v int) (int, error) {
+			return v, nil
+		})
+
+		var got []int
+		for v := range heartbeat.Then(wedged, sink)(gen(100)) {
+			got = append(got, v)
+		}
+
+		require.Equal(t, []int{0}, got)
+		h, ok := r.Get("wedged")
+		require.True(t, ok)
+		require.Equal(t, heartbeat.StateExpired, h.State())
+		require.ErrorContains(t, context.Cause(h.Ctx()), `heartbeat: timeout of "wedged": no beat for `)
+		h, ok = r.Get("sink")
+		require.True(t, ok)
+		require.Equal(t, heartbeat.StateClosed, h.State())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+
+		r := heartbeat.NewRegistry()
+		errFailed := errors.New("failed")
+		failing := heartbeat.Stage(heartbeat.WithRegistry(context.Background(), r), "failing", time.Minute, opts,
+			func(_ context.Context, v int) (int, error) {
+				return 0, errFailed
+			})
+
+		for range failing(gen(10)) {
+			t.Fatal("no output expected")
+		}
+
+		h, _ := r.Get("failing")
+		var serr *heartbeat.StageError
+		require.ErrorAs(t, context.Cause(h.Ctx()), &serr)
+		require.Equal(t, "failing", serr.Stage)
+		require.ErrorIs(t, serr, errFailed)
+		require.Equal(t, heartbeat.StateClosed, h.State())
+	})
+	t.Run("input not closed", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		failing := heartbeat.Stage(ctx, "failing", time.Minute, opts, func(_ context.Context, v int) (int, error) {
+			return 0, errors.New("failed")
+		})
+
+		in := make(chan int)
+		out := failing(in)
+		in <- 0
+		for range out {
+			t.Fatal("no output expected")
+		}
+
+		// The remaining input is discarded.
+		select {
+		case in <- 1:
+		case <-time.After(time.Second):
+			t.Fatal("the input is not drained")
+		}
+
+		// The producer stops sending on ctx.Done, the stage doesn't wait for the input to be closed.
+		cancel()
+		require.Eventually(t, func() bool {
+			select {
+			case in <- 2:
+				return false
+			default:
+				return true
+			}
+		}, time.Second, time.Millisecond)
+	})
+}
@@ -0,0 +1,165 @@
+package heartbeat
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Stats is a snapshot of the Heartbeat state.
+type Stats struct {
+	// Timeout is the timeout in effect, see Options.TimeoutFunc.
+	Timeout time.Duration
+	// TimeoutScale is the factor the timeouts were multiplied by, see SetTimeoutScale.
+	TimeoutScale float64
+	// Idle is the time passed since the last beat.
+	Idle time.Duration
+	// Remaining is the time left until the timeout passes, negative or zero once it has passed.
+	Remaining time.Duration
+	// LastBeat is the time of the last beat.
+	LastBeat time.Time
+	// State is the lifecycle state.
+	State State
+	// Reason is the reason of the expiry, ReasonNone while the Heartbeat hasn't expired.
+	Reason CancelReason
+	// Phase is the current phase, see Heartbeat.SetPhase.
+	Phase string
+	// LastBeatLabel is the label of the last labeled beat, see Heartbeat.BeatLabeled.
+	LastBeatLabel string
+	// Beats is the number of beats, not counting the implicit one on creation.
+	Beats uint64
+	// Checks is the number of the timeout checks done.
+	Checks uint64
+	// AcceptedEvents is the number of values accepted as beats by WatchFunc.
+	AcceptedEvents uint64
+	// RejectedEvents is the number of values rejected by WatchFunc.
+	RejectedEvents uint64
+	// BeatsAfterDone is the number of beats ignored because the Heartbeat was expired, closed or cancelled.
+	BeatsAfterDone uint64
+	// NearMissBeats is the number of beats arriving close to the timeout, see Options.NearMissFraction.
+	NearMissBeats uint64
+	// AcceptedTokens and DuplicateTokens are the numbers of the BeatToken() calls counted as beats
+	// and ignored as duplicates.
+	AcceptedTokens  uint64
+	DuplicateTokens uint64
+	// SkewedBeats is the number of BeatAt() calls with a time in the future, clamped to the time of the call.
+	SkewedBeats uint64
+	// MaxCheckLateness and MeanCheckLateness are the lateness of the periodic checks since they were due,
+	// see Options.StarvationHook.
+	MaxCheckLateness  time.Duration
+	MeanCheckLateness time.Duration
+	// CheckLatencyP50 and CheckLatencyP99 are the percentiles of the check durations, hooks included,
+	// rounded up to a power of two nanoseconds. They are zero unless Options.TrackCheckLatency is set.
+	CheckLatencyP50 time.Duration
+	CheckLatencyP99 time.Duration
+}
+
+// Stats returns a snapshot of the Heartbeat state.
+func (h *Heartbeat) Stats() Stats {
+	now := time.Now()
+	last := h.lastBeatTime()
+	idle := now.Sub(last)
+	if idle < 0 {
+		idle = 0
+	}
+
+	s := Stats{
+		Timeout:   h.currentTimeout(),
+		Idle:      idle,
+		Remaining: h.left(now, idle),
+		LastBeat:  last,
+		State:     h.State(),
+		Reason:    h.CancelReason(),
+		Phase:     h.Phase(),
+		Beats:     h.beats.Load(),
+		Checks:    h.checks.Load(),
+
+		LastBeatLabel: h.LastBeatLabel(),
+		TimeoutScale:  h.scale,
+
+		AcceptedEvents: h.acceptedEvents.Load(),
+		RejectedEvents: h.rejectedEvents.Load(),
+		BeatsAfterDone: h.beatsAfterDone.Load(),
+		NearMissBeats:  h.nearMisses.Load(),
+		SkewedBeats:    h.skewedBeats.Load(),
+
+		AcceptedTokens:  h.acceptedTokens.Load(),
+		DuplicateTokens: h.duplicateTokens.Load(),
+	}
+	if n := h.lateCount.Load(); n > 0 {
+		s.MaxCheckLateness = time.Duration(h.lateMax.Load())
+		s.MeanCheckLateness = time.Duration(h.lateSum.Load() / n)
+	}
+	if h.latency != nil {
+		s.CheckLatencyP50 = h.latency.percentile(0.5)
+		s.CheckLatencyP99 = h.latency.percentile(0.99)
+	}
+
+	return s
+}
+
+// MarshalJSON encodes the durations as strings like "1.5s" and the times in RFC 3339 format.
+// The phase and the last beat label are omitted unless set and the check latencies are omitted unless tracked.
+func (s Stats) MarshalJSON() ([]byte, error) {
+	var p50, p99 string
+	if s.CheckLatencyP50 > 0 {
+		p50, p99 = s.CheckLatencyP50.String(), s.CheckLatencyP99.String()
+	}
+
+	return json.Marshal(struct {
+		Timeout   string `json:"timeout"`
+		Idle      string `json:"idle"`
+		Remaining string `json:"remaining"`
+		LastBeat  string `json:"last_beat"`
+		State     string `json:"state"`
+		Reason    string `json:"reason"`
+		Phase     string `json:"phase,omitempty"`
+		Beats     uint64 `json:"beats"`
+		Checks    uint64 `json:"checks"`
+
+		LastBeatLabel string  `json:"last_beat_label,omitempty"`
+		TimeoutScale  float64 `json:"timeout_scale"`
+
+		AcceptedEvents uint64 `json:"accepted_events"`
+		RejectedEvents uint64 `json:"rejected_events"`
+		BeatsAfterDone uint64 `json:"beats_after_done"`
+		NearMissBeats  uint64 `json:"near_miss_beats"`
+		SkewedBeats    uint64 `json:"skewed_beats"`
+
+		AcceptedTokens  uint64 `json:"accepted_tokens"`
+		DuplicateTokens uint64 `json:"duplicate_tokens"`
+
+		MaxCheckLateness  string `json:"max_check_lateness"`
+		MeanCheckLateness string `json:"mean_check_lateness"`
+
+		CheckLatencyP50 string `json:"check_latency_p50,omitempty"`
+		CheckLatencyP99 string `json:"check_latency_p99,omitempty"`
+	}{
+		Timeout:   s.Timeout.String(),
+		Idle:      s.Idle.String(),
+		Remaining: s.Remaining.String(),
+		LastBeat:  s.LastBeat.Format(time.RFC3339Nano),
+		State:     s.State.String(),
+		Reason:    s.Reason.String(),
+		Phase:     s.Phase,
+		Beats:     s.Beats,
+		Checks:    s.Checks,
+
+		LastBeatLabel: s.LastBeatLabel,
+		TimeoutScale:  s.TimeoutScale,
+
+		AcceptedEvents: s.AcceptedEvents,
+		RejectedEvents: s.RejectedEvents,
+		BeatsAfterDone: s.BeatsAfterDone,
+		NearMissBeats:  s.NearMissBeats,
+		SkewedBeats:    s.SkewedBeats,
+
+		AcceptedTokens:  s.AcceptedTokens,
+		DuplicateTokens: s.DuplicateTokens,
+
+		MaxCheckLateness:  s.MaxCheckLateness.String(),
+		MeanCheckLateness: s.MeanCheckLateness.String(),
+
+		CheckLatencyP50: p50,
+		CheckLatencyP99: p99,
+	})
+}
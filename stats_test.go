@@ -0,0 +1,134 @@
+package heartbeat_test
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+func TestHeartbeat_Stats(t *testing.T) {
+	t.Parallel()
+
+	h := heartbeat.New(context.Background(), time.Second, &heartbeat.Options{
+		CheckInterval: 20 * time.Millisecond,
+	})
+	defer h.Close()
+
+	h.Beat()
+	h.Beat()
+	time.Sleep(100 * time.Millisecond)
+	stats := h.Stats()
+
+	require.Equal(t, time.Second, stats.Timeout)
+	require.InDelta(t, 100*time.Millisecond, stats.Idle, float64(20*time.Millisecond))
+	require.Equal(t, stats.Timeout, stats.Idle+stats.Remaining)
+	require.WithinDuration(t, time.Now().Add(-stats.Idle), stats.LastBeat, 10*time.Millisecond)
+	require.Equal(t, heartbeat.StateRunning, stats.State)
+	require.Equal(t, uint64(2), stats.Beats)
+	require.GreaterOrEqual(t, stats.Checks, uint64(3))
+
+	h.Close()
+	require.Equal(t, heartbeat.StateClosed, h.Stats().State)
+}
+
+func TestHeartbeat_Stats_checkLatency(t *testing.T) {
+	t.Parallel()
+
+	h := heartbeat.New(context.Background(), time.Second, &heartbeat.Options{
+		CheckInterval:     10 * time.Millisecond,
+		TrackCheckLatency: true,
+		CheckHook: func(_, _, _ time.Duration) {
+			time.Sleep(5 * time.Millisecond)
+		},
+	})
+	defer h.Close()
+
+	time.Sleep(200 * time.Millisecond)
+	stats := h.Stats()
+
+	// 5ms falls into the bucket up to 2^23ns, about 8.4ms.
+	require.GreaterOrEqual(t, stats.CheckLatencyP50, 5*time.Millisecond)
+	require.LessOrEqual(t, stats.CheckLatencyP50, 17*time.Millisecond)
+	require.GreaterOrEqual(t, stats.CheckLatencyP99, stats.CheckLatencyP50)
+
+	untracked := heartbeat.New(context.Background(), time.Second, &heartbeat.Options{
+		CheckInterval: 10 * time.Millisecond,
+	})
+	defer untracked.Close()
+	time.Sleep(50 * time.Millisecond)
+	require.Zero(t, untracked.Stats().CheckLatencyP50)
+}
+
+func TestStats_MarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	stats := heartbeat.Stats{
+		Timeout:   time.Minute,
+		Idle:      1500 * time.Millisecond,
+		Remaining: 58500 * time.Millisecond,
+		LastBeat:  time.Date(2023, 6, 1, 12, 30, 0, 0, time.UTC),
+		State:     heartbeat.StateExpired,
+		Reason:    heartbeat.ReasonIdle,
+		Phase:     "uploading",
+		Beats:     3,
+		Checks:    7,
+
+		LastBeatLabel: "chunk",
+		TimeoutScale:  1.5,
+
+		AcceptedEvents: 2,
+		RejectedEvents: 1,
+		BeatsAfterDone: 4,
+		NearMissBeats:  5,
+		SkewedBeats:    6,
+
+		AcceptedTokens:  7,
+		DuplicateTokens: 8,
+
+		MaxCheckLateness:  3 * time.Millisecond,
+		MeanCheckLateness: time.Millisecond,
+	}
+
+	data, err := json.Marshal(stats)
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"timeout": "1m0s",
+		"timeout_scale": 1.5,
+		"idle": "1.5s",
+		"remaining": "58.5s",
+		"last_beat": "2023-06-01T12:30:00Z",
+		"state": "expired",
+		"reason": "idle",
+		"phase": "uploading",
+		"beats": 3,
+		"checks": 7,
+		"last_beat_label": "chunk",
+		"accepted_events": 2,
+		"rejected_events": 1,
+		"beats_after_done": 4,
+		"near_miss_beats": 5,
+		"skewed_beats": 6,
+		"accepted_tokens": 7,
+		"duplicate_tokens": 8,
+		"max_check_lateness": "3ms",
+		"mean_check_lateness": "1ms"
+	}`, string(data))
+}
+
+func TestStats_MarshalJSON_checkLatency(t *testing.T) {
+	t.Parallel()
+
+	data, err := json.Marshal(heartbeat.Stats{
+		CheckLatencyP50: 1024 * time.Nanosecond,
+		CheckLatencyP99: 8 * time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	var fields map[string]any
+	require.NoError(t, json.Unmarshal(data, &fields))
+	require.Equal(t, "1.024µs", fields["check_latency_p50"])
+	require.Equal(t, "8ms", fields["check_latency_p99"])
+}
@@ -0,0 +1,69 @@
+package heartbeat
+
+import "time"
+
+// Stricter derives a Heartbeat applying a shorter timeout, e.g. during a critical section, to the beats
+// of h: the derived one reads the last beat of h, so the code doesn't have to beat it separately.
+// Its context is derived from Ctx(). Its expiry cancels only its own context,
+// unless Options.StricterCancelsParent is set for h.
+// release stops beating the derived Heartbeat and closes it.
+func (h *Heartbeat) Stricter(timeout time.Duration) (strict *Heartbeat, release func()) {
+	// The timeout is scaled like the timeout of h, see Options.TimeoutScale.
+	strict = newHeartbeat(h.Ctx(), timeout, &Options{
+		CheckInterval: min(tenth(h.scaled(timeout)), h.checkInterval),
+		TimeoutScale:  h.scale,
+	})
+	strict.strictOf = h.heartbeat
+	if h.strictParent {
+		strict.cancelFunc = func(cause error) {
+			now := time.Now()
+			idle := h.idle(now)
+			h.expire(now, ReasonForced, cause, idle, h.left(now, idle))
+		}
+	}
+	strict.start()
+
+	h.follow(strict.heartbeat)
+	return strict, func() {
+		h.unfollow(strict.heartbeat)
+		strict.Close()
+	}
+}
+
+// follow makes f count the beats of h too.
+func (h *heartbeat) follow(f *heartbeat) {
+	for {
+		prev := h.followers.Load()
+		var next []*heartbeat
+		if prev != nil {
+			next = append(next, *prev...)
+		}
+		next = append(next, f)
+		if h.followers.CompareAndSwap(prev, &next) {
+			return
+		}
+	}
+}
+
+// unfollow stops counting the beats of h in f.
+func (h *heartbeat) unfollow(f *heartbeat) {
+	for {
+		prev := h.followers.Load()
+		if prev == nil {
+			return
+		}
+		var next []*heartbeat
+		for _, follower := range *prev {
+			if follower != f {
+				next = append(next, follower)
+			}
+		}
+		p := &next
+		if len(next) == 0 {
+			p = nil
+		}
+		if h.followers.CompareAndSwap(prev, p) {
+			return
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package heartbeat_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+func TestHeartbeat_Stricter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("shares the beats", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), time.Minute, nil)
+		defer h.Close()
+
+		strict, release := h.Stricter(50 * time.Millisecond)
+		defer release()
+
+		for i := 0; i < 10; i++ {
+			h.Beat()
+			time.Sleep(20 * time.Millisecond)
+		}
+		require.NoError(t, strict.Ctx().Err())
+		require.Equal(t, uint64(10), strict.Stats().Beats)
+
+		<-strict.Ctx().Done()
+		require.ErrorIs(t, context.Cause(strict.Ctx()), heartbeat.ErrTimeout)
+		require.NoError(t, h.Ctx().Err(), "only the derived one is cancelled")
+
+		release()
+		release()
+		h.Beat()
+		require.Equal(t, uint64(10), strict.Stats().Beats)
+	})
+
+	t.Run("reads the last beat", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), time.Minute, nil)
+		defer h.Close()
+
+		strict, release := h.Stricter(time.Minute)
+		defer release()
+
+		time.Sleep(50 * time.Millisecond)
+		h.BeatWeight(0.1)
+		require.WithinDuration(t, h.Stats().LastBeat, strict.Stats().LastBeat, time.Millisecond)
+		require.GreaterOrEqual(t, strict.Stats().Idle, 40*time.Millisecond, "a partial beat of h is partial for strict too")
+	})
+
+	t.Run("scale", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), time.Minute, &heartbeat.Options{TimeoutScale: 2})
+		defer h.Close()
+
+		strict, release := h.Stricter(time.Second)
+		defer release()
+		require.Equal(t, 2*time.Second, strict.Stats().Timeout)
+		require.Equal(t, 2.0, strict.Stats().TimeoutScale)
+	})
+
+	t.Run("release", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), time.Minute, nil)
+		defer h.Close()
+
+		strict, release := h.Stricter(50 * time.Millisecond)
+		other, releaseOther := h.Stricter(time.Minute)
+		defer releaseOther()
+		release()
+
+		require.Equal(t, heartbeat.StateClosed, strict.State())
+		h.Beat()
+		require.Equal(t, uint64(1), other.Stats().Beats)
+	})
+
+	t.Run("cancels parent", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), time.Minute, &heartbeat.Options{StricterCancelsParent: true})
+		defer h.Close()
+
+		strict, release := h.Stricter(50 * time.Millisecond)
+		defer release()
+
+		<-h.Ctx().Done()
+		require.Equal(t, heartbeat.ReasonForced, h.CancelReason())
+		cause := context.Cause(h.Ctx())
+		require.ErrorIs(t, cause, heartbeat.ErrTimeout)
+		require.ErrorContains(t, cause, "forced: heartbeat: timeout: no beat for ")
+		require.Equal(t, heartbeat.StateExpired, strict.State())
+	})
+}
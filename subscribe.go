@@ -0,0 +1,92 @@
+package heartbeat
+
+import "sync"
+
+// event is a lifecycle event of a Heartbeat delivered to the Subscriptions.
+type event int
+
+const (
+	eventExpired event = iota
+	eventWarned
+	eventClosed
+	events
+)
+
+// Subscription receives the lifecycle events of a Heartbeat by the channels closed on the events,
+// so that any number of listeners can wait for them, see Heartbeat.Subscribe.
+type Subscription struct {
+	subs *subscriptions
+	c    [events]chan struct{}
+}
+
+// Expired returns the channel closed once the Heartbeat expires.
+func (s *Subscription) Expired() <-chan struct{} {
+	return s.c[eventExpired]
+}
+
+// Warned returns the channel closed the first time the timeout passes, or a slot of Options.Schedule is missed,
+// with Options.DontCancel.
+func (s *Subscription) Warned() <-chan struct{} {
+	return s.c[eventWarned]
+}
+
+// Closed returns the channel closed once the Heartbeat is closed or its parent context is cancelled,
+// unless it expired first.
+func (s *Subscription) Closed() <-chan struct{} {
+	return s.c[eventClosed]
+}
+
+// Unsubscribe stops the delivery of the events, the channels not closed yet stay open.
+func (s *Subscription) Unsubscribe() {
+	s.subs.mu.Lock()
+	defer s.subs.mu.Unlock()
+	delete(s.subs.set, s)
+}
+
+// subscriptions are the Subscriptions to a generation of a Heartbeat.
+type subscriptions struct {
+	mu    sync.Mutex
+	fired [events]bool
+	set   map[*Subscription]struct{}
+}
+
+func (ss *subscriptions) add() *Subscription {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	s := &Subscription{subs: ss}
+	for e := range s.c {
+		s.c[e] = make(chan struct{})
+		if ss.fired[e] {
+			close(s.c[e])
+		}
+	}
+
+	if ss.set == nil {
+		ss.set = make(map[*Subscription]struct{})
+	}
+	ss.set[s] = struct{}{}
+
+	return s
+}
+
+// fire delivers the event to the Subscriptions once, it never blocks on the listeners.
+func (ss *subscriptions) fire(e event) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	if ss.fired[e] {
+		return
+	}
+	ss.fired[e] = true
+	for s := range ss.set {
+		close(s.c[e])
+	}
+}
+
+// Subscribe returns a Subscription to the lifecycle events of the Heartbeat. The events that already happened
+// are observed right away, by the channels already closed. The Subscription is bound to the current context:
+// after Reset(), Subscribe again.
+func (h *Heartbeat) Subscribe() *Subscription {
+	return h.gen.Load().subs.add()
+}
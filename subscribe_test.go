@@ -0,0 +1,79 @@
+package heartbeat_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+// isClosed reports whether c is closed.
+func isClosed(c <-chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestHeartbeat_Subscribe(t *testing.T) {
+	t.Parallel()
+
+	opts := &heartbeat.Options{CheckInterval: 10 * time.Millisecond}
+
+	t.Run("expired", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), 50*time.Millisecond, opts)
+		defer h.Close()
+
+		s1, s2 := h.Subscribe(), h.Subscribe()
+		unsubscribed := h.Subscribe()
+		unsubscribed.Unsubscribe()
+
+		<-s1.Expired()
+		<-s2.Expired()
+		require.False(t, isClosed(unsubscribed.Expired()))
+		require.False(t, isClosed(s1.Closed()))
+
+		// Observed right away after the event.
+		late := h.Subscribe()
+		require.True(t, isClosed(late.Expired()))
+		require.False(t, isClosed(late.Warned()))
+
+		h.Close()
+		require.False(t, isClosed(s1.Closed()), "expired first")
+	})
+
+	t.Run("warned", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), 50*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+			DontCancel:    true,
+		})
+		s := h.Subscribe()
+
+		<-s.Warned()
+		require.False(t, isClosed(s.Expired()))
+
+		h.Close()
+		require.True(t, isClosed(s.Closed()))
+		require.True(t, isClosed(h.Subscribe().Closed()))
+	})
+
+	t.Run("parent cancelled", func(t *testing.T) {
+		t.Parallel()
+
+		parent, cancel := context.WithCancel(context.Background())
+		h := heartbeat.New(parent, time.Minute, opts)
+		defer h.Close()
+		s := h.Subscribe()
+
+		cancel()
+		<-s.Closed()
+		require.False(t, isClosed(s.Expired()))
+	})
+}
@@ -0,0 +1,31 @@
+package heartbeat
+
+import "context"
+
+// Tee returns a Beater forwarding every Beat() to primary and to the others, e.g. for the beats
+// of a sub-operation to count for the job-level Heartbeat too. Ctx() is the context of primary.
+// The nil others are skipped, the closed Heartbeats ignore the beats.
+func Tee(primary Beater, others ...Beater) Beater {
+	if primary == nil {
+		panic("primary beater is required")
+	}
+	return &tee{primary: primary, others: others}
+}
+
+type tee struct {
+	primary Beater
+	others  []Beater
+}
+
+func (t *tee) Beat() {
+	t.primary.Beat()
+	for _, b := range t.others {
+		if b != nil {
+			b.Beat()
+		}
+	}
+}
+
+func (t *tee) Ctx() context.Context {
+	return t.primary.Ctx()
+}
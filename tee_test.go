@@ -0,0 +1,58 @@
+package heartbeat_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+func TestTee(t *testing.T) {
+	t.Parallel()
+
+	primary := heartbeat.New(context.Background(), time.Minute, nil)
+	defer primary.Close()
+	other := heartbeat.New(context.Background(), time.Minute, nil)
+	defer other.Close()
+	closed := heartbeat.New(context.Background(), time.Minute, nil)
+	closed.Close()
+
+	b := heartbeat.Tee(primary, other, nil, closed)
+	require.Equal(t, primary.Ctx(), b.Ctx())
+
+	b.Beat()
+	b.Beat()
+	require.Equal(t, uint64(2), primary.Stats().Beats)
+	require.Equal(t, uint64(2), other.Stats().Beats)
+	require.Equal(t, uint64(2), closed.Stats().BeatsAfterDone)
+
+	require.Panics(t, func() {
+		heartbeat.Tee(nil)
+	})
+}
+
+func TestTee_noAllocs(t *testing.T) {
+	primary := heartbeat.New(context.Background(), time.Minute, nil)
+	defer primary.Close()
+	other := heartbeat.New(context.Background(), time.Minute, nil)
+	defer other.Close()
+
+	require.Zero(t, testing.AllocsPerRun(100, heartbeat.Tee(primary, other).Beat))
+}
+
+func TestOptions_ForwardBeatsTo(t *testing.T) {
+	t.Parallel()
+
+	job := heartbeat.New(context.Background(), time.Minute, nil)
+	defer job.Close()
+
+	step := heartbeat.New(job.Ctx(), time.Minute, &heartbeat.Options{ForwardBeatsTo: job})
+	step.Beat()
+	step.SetPhase("uploading")
+	require.Equal(t, uint64(2), job.Stats().Beats)
+
+	step.Close()
+	step.Beat()
+	require.Equal(t, uint64(2), job.Stats().Beats)
+}
@@ -0,0 +1,60 @@
+package heartbeat
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// DefaultBeatTokens is the default number of the recent tokens remembered by BeatToken.
+const DefaultBeatTokens = 128
+
+// BeatToken is the same as Beat, but a beat with a token seen recently is a duplicate and it is ignored,
+// e.g. for the retried deliveries of a webhook. It reports whether the beat was counted.
+// The Options.BeatTokens most recent tokens are remembered. The counts of the tokens accepted
+// and of the duplicates are reported by Stats().
+func (h *Heartbeat) BeatToken(token string) bool {
+	if h.afterDone() {
+		return false
+	}
+	if !h.tokens.add(token) {
+		h.duplicateTokens.Add(1)
+		return false
+	}
+
+	h.acceptedTokens.Add(1)
+	h.beat(time.Now())
+	return true
+}
+
+// tokenCache is a bounded LRU set of the recent tokens.
+type tokenCache struct {
+	mu    sync.Mutex
+	size  int
+	order *list.List // the most recent first
+	seen  map[string]*list.Element
+}
+
+// add adds the token and reports whether it is new. A token seen again becomes the most recent.
+func (c *tokenCache) add(token string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.seen[token]; ok {
+		c.order.MoveToFront(e)
+		return false
+	}
+
+	if c.seen == nil {
+		c.order = list.New()
+		c.seen = make(map[string]*list.Element, c.size)
+	}
+	if c.order.Len() >= c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.seen, oldest.Value.(string))
+	}
+	c.seen[token] = c.order.PushFront(token)
+
+	return true
+}
@@ -0,0 +1,49 @@
+package heartbeat_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+func TestHeartbeat_BeatToken(t *testing.T) {
+	t.Parallel()
+
+	h := heartbeat.New(context.Background(), time.Minute, &heartbeat.Options{
+		BeatTokens: 2,
+	})
+	defer h.Close()
+
+	require.True(t, h.BeatToken("a"))
+	require.False(t, h.BeatToken("a"))
+	require.True(t, h.BeatToken("b"))
+	require.False(t, h.BeatToken("a"), "a is still remembered")
+	require.True(t, h.BeatToken("c"), "b is evicted as the least recent")
+	require.True(t, h.BeatToken("b"))
+	h.Beat()
+
+	stats := h.Stats()
+	require.Equal(t, uint64(4), stats.AcceptedTokens)
+	require.Equal(t, uint64(2), stats.DuplicateTokens)
+	require.Equal(t, uint64(5), stats.Beats)
+
+	h.Close()
+	require.False(t, h.BeatToken("d"))
+	require.Equal(t, uint64(4), h.Stats().AcceptedTokens)
+}
+
+func TestHeartbeat_BeatToken_bounded(t *testing.T) {
+	t.Parallel()
+
+	h := heartbeat.New(context.Background(), time.Minute, nil)
+	defer h.Close()
+
+	for i := 0; i < 10*heartbeat.DefaultBeatTokens; i++ {
+		require.True(t, h.BeatToken(strconv.Itoa(i)))
+	}
+	require.False(t, h.BeatToken(strconv.Itoa(10*heartbeat.DefaultBeatTokens-1)))
+	require.True(t, h.BeatToken("0"))
+}
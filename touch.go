@@ -0,0 +1,102 @@
+package heartbeat
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// TouchFile updates the modification time of the file at path every interval while the Heartbeat is alive.
+// It is useful for liveness probes checking the mtime of a file, e.g. Kubernetes exec probes.
+//
+// The file is created if it is missing, but its parent directory must exist.
+// Touching stops as soon as the Heartbeat context is done, and it is skipped while the timeout has passed.
+// stop ends touching and waits for the touching goroutine to exit, after that the file can be safely removed.
+func TouchFile(h *Heartbeat, path string, interval time.Duration) (stop func(), err error) {
+	return TouchFileWithOptions(h, path, interval, nil)
+}
+
+// TouchOptions defines optional parameters of TouchFileWithOptions.
+type TouchOptions struct {
+	// RemoveOnStop removes the file once touching stops, by stop or because the Heartbeat context is done,
+	// e.g. for the probes checking the file exists. The removal is best-effort, stop returns after it.
+	RemoveOnStop bool
+}
+
+// TouchFileWithOptions is the same as TouchFile with the optional parameters, see TouchOptions.
+func TouchFileWithOptions(h *Heartbeat, path string, interval time.Duration, opts *TouchOptions) (stop func(), err error) {
+	if interval <= 0 {
+		panic("positive interval is required")
+	}
+
+	dir := filepath.Dir(path)
+	if _, err := os.Stat(dir); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("heartbeat: touch file: parent directory %s does not exist", dir)
+		}
+		return nil, fmt.Errorf("heartbeat: touch file: %w", err)
+	}
+
+	if err := touch(path); err != nil {
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	exited := make(chan struct{})
+
+	remove := opts != nil && opts.RemoveOnStop
+	go func() {
+		defer close(exited)
+		if remove {
+			defer func() {
+				_ = os.Remove(path)
+			}()
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-h.Ctx().Done():
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				if h.Remaining() > 0 {
+					// The file could be removed by someone else, it will be recreated on the next tick.
+					_ = touch(path)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	stop = func() {
+		once.Do(func() { close(done) })
+		<-exited
+	}
+
+	return stop, nil
+}
+
+func touch(path string) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("heartbeat: touch file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("heartbeat: touch file: %w", err)
+	}
+
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err != nil {
+		return fmt.Errorf("heartbeat: touch file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+package heartbeat_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+func mtime(t *testing.T, path string) time.Time {
+	t.Helper()
+
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	return fi.ModTime()
+}
+
+func TestTouchFile(t *testing.T) {
+	t.Parallel()
+
+	t.Run("creates and touches the file while alive", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "alive")
+
+		h := heartbeat.New(context.Background(), time.Second, nil)
+		defer h.Close()
+
+		stop, err := heartbeat.TouchFile(h, path, 50*time.Millisecond)
+		require.NoError(t, err)
+		defer stop()
+
+		first := mtime(t, path)
+		time.Sleep(200 * time.Millisecond)
+		require.True(t, mtime(t, path).After(first))
+	})
+
+	t.Run("stops touching on expiry", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "alive")
+
+		h := heartbeat.New(context.Background(), 200*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 20 * time.Millisecond,
+		})
+		defer h.Close()
+
+		stop, err := heartbeat.TouchFile(h, path, 20*time.Millisecond)
+		require.NoError(t, err)
+		defer stop()
+
+		<-h.Ctx().Done()
+		time.Sleep(50 * time.Millisecond)
+		expired := mtime(t, path)
+
+		time.Sleep(200 * time.Millisecond)
+		require.Equal(t, expired, mtime(t, path))
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "alive")
+
+		h := heartbeat.New(context.Background(), time.Second, nil)
+		defer h.Close()
+
+		stop, err := heartbeat.TouchFile(h, path, 20*time.Millisecond)
+		require.NoError(t, err)
+
+		stop()
+		stop()
+		require.NoError(t, os.Remove(path))
+
+		time.Sleep(100 * time.Millisecond)
+		require.NoFileExists(t, path)
+	})
+
+	t.Run("remove on stop", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "alive")
+
+		h := heartbeat.New(context.Background(), time.Second, nil)
+		defer h.Close()
+
+		opts := &heartbeat.TouchOptions{RemoveOnStop: true}
+		stop, err := heartbeat.TouchFileWithOptions(h, path, 20*time.Millisecond, opts)
+		require.NoError(t, err)
+		require.FileExists(t, path)
+
+		stop()
+		require.NoFileExists(t, path)
+		stop()
+	})
+
+	t.Run("remove on expiry", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "alive")
+
+		h := heartbeat.New(context.Background(), 50*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 10 * time.Millisecond,
+		})
+		defer h.Close()
+
+		opts := &heartbeat.TouchOptions{RemoveOnStop: true}
+		stop, err := heartbeat.TouchFileWithOptions(h, path, 20*time.Millisecond, opts)
+		require.NoError(t, err)
+		defer stop()
+
+		<-h.Ctx().Done()
+		require.Eventually(t, func() bool {
+			_, err := os.Stat(path)
+			return os.IsNotExist(err)
+		}, time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("missing parent directory", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "missing", "alive")
+
+		h := heartbeat.New(context.Background(), time.Second, nil)
+		defer h.Close()
+
+		_, err := heartbeat.TouchFile(h, path, time.Second)
+		require.ErrorContains(t, err, "parent directory")
+	})
+}
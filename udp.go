@@ -0,0 +1,180 @@
+package heartbeat
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxDatagramSize is the size of the receive buffer, longer datagrams are truncated.
+const maxDatagramSize = 1024
+
+// The bounds of the delay before reading again after a read error, doubled on every consecutive error.
+const (
+	minReadBackoff = 5 * time.Millisecond
+	maxReadBackoff = time.Second
+)
+
+// UDPOptions defines optional parameters of ListenUDP.
+type UDPOptions struct {
+	// Token is the shared secret every datagram must start with.
+	// If empty, every non-empty datagram is a beat.
+	Token string
+}
+
+// UDPListener beats a Beater on every valid datagram received on its socket.
+// It lets processes that don't share memory with the supervisor report their progress.
+type UDPListener struct {
+	conn  *net.UDPConn
+	h     Beater
+	token []byte
+
+	accepted atomic.Uint64
+	rejected atomic.Uint64
+
+	closing   chan struct{}
+	closeOnce sync.Once
+	exited    chan struct{}
+}
+
+// ListenUDP binds a UDP socket to addr and beats h on every valid datagram received, see UDPOptions.
+// Invalid datagrams are ignored and counted in Rejected().
+// The listener is closed when the context of h is done or when Close() is called.
+func ListenUDP(addr string, h Beater, opts *UDPOptions) (*UDPListener, error) {
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("heartbeat: resolve udp address: %w", err)
+	}
+
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return nil, fmt.Errorf("heartbeat: listen udp: %w", err)
+	}
+
+	l := &UDPListener{
+		conn:    conn,
+		h:       h,
+		closing: make(chan struct{}),
+		exited:  make(chan struct{}),
+	}
+	if opts != nil {
+		l.token = []byte(opts.Token)
+	}
+
+	go l.serve()
+
+	return l, nil
+}
+
+// Addr returns the local address of the listener.
+func (l *UDPListener) Addr() net.Addr {
+	return l.conn.LocalAddr()
+}
+
+// Accepted returns the number of datagrams accepted as beats.
+func (l *UDPListener) Accepted() uint64 {
+	return l.accepted.Load()
+}
+
+// Rejected returns the number of ignored datagrams, either empty or missing the token.
+func (l *UDPListener) Rejected() uint64 {
+	return l.rejected.Load()
+}
+
+// Close closes the listener and waits for the receiving goroutine to exit.
+func (l *UDPListener) Close() error {
+	l.closeOnce.Do(func() { close(l.closing) })
+	err := l.conn.Close()
+	<-l.exited
+	if errors.Is(err, net.ErrClosed) {
+		return nil
+	}
+	return err
+}
+
+func (l *UDPListener) serve() {
+	defer close(l.exited)
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go func() {
+		select {
+		case <-l.h.Ctx().Done():
+			_ = l.conn.Close()
+		case <-stop:
+		}
+	}()
+
+	buf := make([]byte, maxDatagramSize)
+	var backoff time.Duration
+	for {
+		n, _, err := l.conn.ReadFromUDP(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			// The error may persist, e.g. ENOBUFS, don't spin on it.
+			backoff = min(max(2*backoff, minReadBackoff), maxReadBackoff)
+			t := time.NewTimer(backoff)
+			select {
+			case <-t.C:
+			case <-l.closing:
+				t.Stop()
+				return
+			case <-l.h.Ctx().Done():
+				t.Stop()
+				return
+			}
+			continue
+		}
+		backoff = 0
+
+		if n == 0 || !bytes.HasPrefix(buf[:n], l.token) {
+			l.rejected.Add(1)
+			continue
+		}
+
+		l.accepted.Add(1)
+		l.h.Beat()
+	}
+}
+
+// SendUDP sends a beat datagram carrying the token to addr every interval, starting immediately.
+// It is the client side of ListenUDP. Send errors are ignored, as the listener may be temporarily unavailable.
+// SendUDP blocks until ctx is done and returns the context error.
+func SendUDP(ctx context.Context, addr string, interval time.Duration, token string) error {
+	if interval <= 0 {
+		panic("positive interval is required")
+	}
+
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return fmt.Errorf("heartbeat: dial udp: %w", err)
+	}
+	defer conn.Close()
+
+	msg := []byte(token)
+	if len(msg) == 0 {
+		// Empty datagrams are rejected by the listener.
+		msg = []byte("beat")
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		_, _ = conn.Write(msg)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
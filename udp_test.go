@@ -0,0 +1,77 @@
+package heartbeat_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"net"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+func TestListenUDP(t *testing.T) {
+	t.Parallel()
+
+	t.Run("beats keep the heartbeat alive", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), 200*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 20 * time.Millisecond,
+		})
+		defer h.Close()
+
+		l, err := heartbeat.ListenUDP("127.0.0.1:0", h, &heartbeat.UDPOptions{Token: "secret"})
+		require.NoError(t, err)
+		defer l.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, heartbeat.SendUDP(ctx, l.Addr().String(), 50*time.Millisecond, "secret"), context.DeadlineExceeded)
+
+		require.NoError(t, h.Ctx().Err())
+		require.GreaterOrEqual(t, l.Accepted(), uint64(5))
+		require.Zero(t, l.Rejected())
+	})
+
+	t.Run("wrong token", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), 200*time.Millisecond, &heartbeat.Options{
+			CheckInterval: 20 * time.Millisecond,
+		})
+		defer h.Close()
+
+		l, err := heartbeat.ListenUDP("127.0.0.1:0", h, &heartbeat.UDPOptions{Token: "secret"})
+		require.NoError(t, err)
+		defer l.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+		go func() {
+			_ = heartbeat.SendUDP(ctx, l.Addr().String(), 50*time.Millisecond, "wrong")
+		}()
+
+		conn, err := net.Dial("udp", l.Addr().String())
+		require.NoError(t, err)
+		defer conn.Close()
+		_, err = conn.Write(nil)
+		require.NoError(t, err)
+
+		<-h.Ctx().Done()
+		require.ErrorIs(t, context.Cause(h.Ctx()), heartbeat.ErrTimeout)
+		require.Zero(t, l.Accepted())
+		require.GreaterOrEqual(t, l.Rejected(), uint64(3))
+	})
+
+	t.Run("closed with the heartbeat", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), time.Second, nil)
+
+		l, err := heartbeat.ListenUDP("127.0.0.1:0", h, nil)
+		require.NoError(t, err)
+
+		h.Close()
+		require.NoError(t, l.Close())
+	})
+}
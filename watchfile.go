@@ -0,0 +1,76 @@
+package heartbeat
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+// WatchFile polls the file at path every pollInterval and beats h whenever the file changed since the last poll.
+// It is useful for the tools reporting their progress only to a log file.
+//
+// Any change of the size or the modification time counts as activity, so does the file rotation
+// (the file is replaced or truncated) and the file appearing after it was missing. Comparing the size as well
+// makes the growth visible on the filesystems with coarse modification time granularity.
+// A missing file is not an error, it is not activity either.
+//
+// Watching stops when stop is called or when the context of h is done.
+func WatchFile(h Beater, path string, pollInterval time.Duration) (stop func(), err error) {
+	if pollInterval <= 0 {
+		panic("positive poll interval is required")
+	}
+
+	prev, err := os.Stat(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("heartbeat: watch file: %w", err)
+	}
+
+	done := make(chan struct{})
+	exited := make(chan struct{})
+
+	go func() {
+		defer close(exited)
+
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-h.Ctx().Done():
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+				cur, err := os.Stat(path)
+				if err != nil {
+					// Missing or inaccessible, the next appearance will be a change.
+					prev = nil
+					continue
+				}
+
+				if fileChanged(prev, cur) {
+					h.Beat()
+				}
+				prev = cur
+			}
+		}
+	}()
+
+	var once sync.Once
+	stop = func() {
+		once.Do(func() { close(done) })
+		<-exited
+	}
+
+	return stop, nil
+}
+
+func fileChanged(prev, cur fs.FileInfo) bool {
+	return prev == nil ||
+		!os.SameFile(prev, cur) ||
+		prev.Size() != cur.Size() ||
+		!prev.ModTime().Equal(cur.ModTime())
+}
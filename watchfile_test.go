@@ -0,0 +1,86 @@
+package heartbeat_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+func TestWatchFile(t *testing.T) {
+	t.Parallel()
+
+	newHeartbeat := func() *heartbeat.Heartbeat {
+		h := heartbeat.New(context.Background(), time.Second, &heartbeat.Options{
+			CheckInterval: 50 * time.Millisecond,
+			DontCancel:    true,
+		})
+		t.Cleanup(h.Close)
+		return h
+	}
+
+	// idleAfter makes h idle for a while, applies change and reports whether it was seen as activity.
+	idleAfter := func(t *testing.T, h *heartbeat.Heartbeat, change func()) bool {
+		time.Sleep(300 * time.Millisecond)
+		change()
+		time.Sleep(50 * time.Millisecond)
+		return h.Remaining() > 800*time.Millisecond
+	}
+
+	t.Run("growth, rotation, missing file", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "log")
+		require.NoError(t, os.WriteFile(path, []byte("start\n"), 0o644))
+
+		h := newHeartbeat()
+		stop, err := heartbeat.WatchFile(h, path, 10*time.Millisecond)
+		require.NoError(t, err)
+		defer stop()
+
+		mtime := time.Now().Add(-time.Hour)
+		require.True(t, idleAfter(t, h, func() {
+			f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+			require.NoError(t, err)
+			_, err = f.WriteString("progress\n")
+			require.NoError(t, err)
+			require.NoError(t, f.Close())
+			// Simulate coarse mtime granularity, only the size changes.
+			require.NoError(t, os.Chtimes(path, mtime, mtime))
+		}), "growth")
+
+		require.True(t, idleAfter(t, h, func() {
+			require.NoError(t, os.Rename(path, path+".1"))
+			require.NoError(t, os.WriteFile(path, []byte("progress\n"), 0o644))
+			require.NoError(t, os.Chtimes(path, mtime, mtime))
+		}), "rotation")
+
+		require.False(t, idleAfter(t, h, func() {
+			require.NoError(t, os.Remove(path))
+		}), "removal")
+
+		require.True(t, idleAfter(t, h, func() {
+			require.NoError(t, os.WriteFile(path, nil, 0o644))
+		}), "reappearance")
+
+		require.False(t, idleAfter(t, h, func() {}), "no change")
+	})
+
+	t.Run("stop", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "log")
+
+		h := newHeartbeat()
+		stop, err := heartbeat.WatchFile(h, path, 10*time.Millisecond)
+		require.NoError(t, err)
+		stop()
+
+		require.False(t, idleAfter(t, h, func() {
+			require.NoError(t, os.WriteFile(path, nil, 0o644))
+		}))
+	})
+}
@@ -0,0 +1,61 @@
+package heartbeat
+
+import "sync"
+
+// eventCounter is implemented by Heartbeat to count the events of WatchFunc in Stats.
+type eventCounter interface {
+	countEvent(accepted bool)
+}
+
+func (h *heartbeat) countEvent(accepted bool) {
+	if accepted {
+		h.acceptedEvents.Add(1)
+	} else {
+		h.rejectedEvents.Add(1)
+	}
+}
+
+// WatchFunc beats h for every value received from ch that accept returns true for, e.g. to ignore "retrying"
+// progress events. If accept is nil, every value is a beat. If h is a *Heartbeat, the accepted and rejected values
+// are counted in Stats.
+//
+// WatchFunc consumes the values, so it needs its own subscription to the events, it must not share ch
+// with other consumers.
+// Watching stops when ch is closed, when the context of h is done, or when stop is called.
+func WatchFunc[T any](h Beater, ch <-chan T, accept func(T) bool) (stop func()) {
+	counter, _ := h.(eventCounter)
+
+	done := make(chan struct{})
+	exited := make(chan struct{})
+
+	go func() {
+		defer close(exited)
+
+		for {
+			select {
+			case <-h.Ctx().Done():
+				return
+			case <-done:
+				return
+			case v, ok := <-ch:
+				if !ok {
+					return
+				}
+
+				accepted := accept == nil || accept(v)
+				if accepted {
+					h.Beat()
+				}
+				if counter != nil {
+					counter.countEvent(accepted)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+		<-exited
+	}
+}
@@ -0,0 +1,70 @@
+package heartbeat_test
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"ytils.dev/heartbeat"
+)
+
+type progressEvent struct {
+	retrying bool
+}
+
+func TestWatchFunc(t *testing.T) {
+	t.Parallel()
+
+	t.Run("accepted and rejected events", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), time.Second, &heartbeat.Options{
+			CheckInterval: 50 * time.Millisecond,
+			DontCancel:    true,
+		})
+		defer h.Close()
+
+		ch := make(chan progressEvent)
+		stop := heartbeat.WatchFunc(h, ch, func(e progressEvent) bool { return !e.retrying })
+		defer stop()
+
+		time.Sleep(300 * time.Millisecond)
+		ch <- progressEvent{retrying: true}
+		ch <- progressEvent{retrying: true}
+		time.Sleep(10 * time.Millisecond)
+		require.Less(t, h.Remaining(), 800*time.Millisecond)
+
+		ch <- progressEvent{}
+		time.Sleep(10 * time.Millisecond)
+		require.Greater(t, h.Remaining(), 800*time.Millisecond)
+
+		stats := h.Stats()
+		require.Equal(t, uint64(1), stats.AcceptedEvents)
+		require.Equal(t, uint64(2), stats.RejectedEvents)
+		require.Equal(t, uint64(1), stats.Beats)
+	})
+
+	t.Run("exits when the channel is closed", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), time.Second, nil)
+		defer h.Close()
+
+		ch := make(chan int)
+		stop := heartbeat.WatchFunc(h, ch, nil)
+
+		close(ch)
+		stop() // waits for the watching goroutine to exit
+	})
+
+	t.Run("exits when the heartbeat is closed", func(t *testing.T) {
+		t.Parallel()
+
+		h := heartbeat.New(context.Background(), time.Second, nil)
+
+		stop := heartbeat.WatchFunc(h, make(chan int), nil)
+
+		h.Close()
+		stop()
+	})
+}